@@ -1,9 +1,14 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"portfolio-backend/services"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -12,8 +17,20 @@ func SetupRoutes(e *echo.Echo) {
 	e.GET("/health", healthCheck)
 	api := e.Group("/api")
 	api.GET("/commits", getCommits)
+	api.GET("/activities", getActivities)
+	api.GET("/statuses", getStatuses)
 	api.GET("/version", getVersion)
+	api.GET("/diagnostics/github", getGitHubDiagnostics)
+	api.GET("/sync/status", getSyncStatus)
 	api.GET("/projects", getProjects)
+	api.GET("/projects/:slug", getProject)
+	api.GET("/stats", getStats)
+	api.GET("/stats/heatmap", getStatsHeatmap)
+	api.GET("/stats/repos", getStatsRepos)
+	api.GET("/stats/distribution", getStatsDistribution)
+	api.GET("/stats/streaks", getStatsStreaks)
+	api.GET("/stats/languages", getStatsLanguages)
+	api.POST("/webhooks/github", githubWebhook)
 }
 
 func healthCheck(c echo.Context) error {
@@ -24,26 +41,228 @@ func getVersion(c echo.Context) error {
 	return c.JSON(http.StatusOK, services.GetVersionFromTag())
 }
 
+func getGitHubDiagnostics(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"client_initialized": services.GetGitHubClient() != nil,
+		"rate_limits":        services.GetAccountRateLimits(),
+	})
+}
+
+func getSyncStatus(c echo.Context) error {
+	return c.JSON(http.StatusOK, services.GetCrawlStatus())
+}
+
 func getCommits(c echo.Context) error {
 	// Get page and limit from query parameters
 	page, _ := strconv.Atoi(c.QueryParam("page"))
 	limit, _ := strconv.Atoi(c.QueryParam("limit"))
 
 	// Set default values if not provided
+	if limit < 1 || limit > 100 {
+		limit = 20 // Default limit
+	}
+
+	filter, err := parseCommitFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Page based pagination is kept for existing clients, cursors are used otherwise
+	if c.QueryParam("page") == "" {
+		return getCommitsByCursor(c, limit, filter)
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	commits, totalCount, err := services.GetAllCommitsFromCache(page, limit, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	response := map[string]interface{}{
+		"commits":     commits,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+		"filters":     commitFilterResponse(filter),
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func getCommitsByCursor(c echo.Context, limit int, filter services.CommitFilter) error {
+	result, err := services.GetCommitsPageFromCache(c.QueryParam("cursor"), limit, filter)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	var links []string
+	if result.NextCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, cursorURL(c, result.NextCursor)))
+	}
+	if result.PrevCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, cursorURL(c, result.PrevCursor)))
+	}
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	response := map[string]interface{}{
+		"commits":     result.Commits,
+		"limit":       limit,
+		"total_count": result.TotalCount,
+		"next_cursor": result.NextCursor,
+		"prev_cursor": result.PrevCursor,
+		"filters":     commitFilterResponse(filter),
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// cursorURL returns the current request URL pointing at another cursor
+func cursorURL(c echo.Context, cursor string) string {
+	query := url.Values{}
+	for key, values := range c.QueryParams() {
+		query[key] = values
+	}
+	query.Set("cursor", cursor)
+	return c.Request().URL.Path + "?" + query.Encode()
+}
+
+// parseCommitFilter reads the filtering query parameters of /api/commits
+func parseCommitFilter(c echo.Context) (services.CommitFilter, error) {
+	filter := services.DefaultCommitFilter()
+	filter.Repo = c.QueryParam("repo")
+	filter.Query = c.QueryParam("q")
+	filter.Branch = c.QueryParam("branch")
+	if types := c.QueryParam("type"); types != "" {
+		for _, commitType := range strings.Split(types, ",") {
+			if commitType = strings.TrimSpace(commitType); commitType != "" {
+				filter.Types = append(filter.Types, strings.ToLower(commitType))
+			}
+		}
+	}
+
+	var err error
+	if filter.Since, err = parseTimeParam(c.QueryParam("since"), false); err != nil {
+		return filter, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseTimeParam(c.QueryParam("until"), true); err != nil {
+		return filter, fmt.Errorf("invalid until: %w", err)
+	}
+	if value := c.QueryParam("include_private"); value != "" {
+		if filter.IncludePrivate, err = strconv.ParseBool(value); err != nil {
+			return filter, fmt.Errorf("invalid include_private: %w", err)
+		}
+	}
+	if value := c.QueryParam("exclude_merges"); value != "" {
+		if filter.ExcludeMerges, err = strconv.ParseBool(value); err != nil {
+			return filter, fmt.Errorf("invalid exclude_merges: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// parseTimeParam accepts RFC3339 timestamps or plain dates. A plain date used as an
+// upper bound covers the whole day.
+func parseTimeParam(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.New("expected an RFC3339 timestamp or a YYYY-MM-DD date")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
+
+// commitFilterResponse echoes the filters applied to /api/commits
+func commitFilterResponse(filter services.CommitFilter) map[string]interface{} {
+	filters := map[string]interface{}{
+		"include_private": filter.IncludePrivate,
+		"exclude_merges":  filter.ExcludeMerges,
+	}
+	if filter.Repo != "" {
+		filters["repo"] = filter.Repo
+	}
+	if filter.Query != "" {
+		filters["q"] = filter.Query
+	}
+	if len(filter.Types) > 0 {
+		filters["type"] = filter.Types
+	}
+	if filter.Branch != "" {
+		filters["branch"] = filter.Branch
+	}
+	if !filter.Since.IsZero() {
+		filters["since"] = filter.Since.Format(time.RFC3339)
+	}
+	if !filter.Until.IsZero() {
+		filters["until"] = filter.Until.Format(time.RFC3339)
+	}
+	return filters
+}
+
+func getActivities(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
 	if page < 1 {
 		page = 1
 	}
 	if limit < 1 || limit > 100 {
-		limit = 20 // Default limit
+		limit = 20
+	}
+
+	// Optional comma separated list of sources, all registered sources by default
+	var sources []string
+	if sourceParam := c.QueryParam("source"); sourceParam != "" {
+		sources = strings.Split(sourceParam, ",")
 	}
 
-	commits, totalCount, err := services.GetAllCommitsFromCache(page, limit)
+	activities, totalCount, err := services.GetActivitiesFromCache(page, limit, sources)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
 	response := map[string]interface{}{
-		"commits":     commits,
+		"activities":  activities,
+		"page":        page,
+		"limit":       limit,
+		"total_count": totalCount,
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func getStatuses(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	statuses, totalCount, err := services.GetStatusesFromCache(page, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	response := map[string]interface{}{
+		"statuses":    statuses,
 		"page":        page,
 		"limit":       limit,
 		"total_count": totalCount,
@@ -53,10 +272,109 @@ func getCommits(c echo.Context) error {
 }
 
 func getProjects(c echo.Context) error {
-	projects, err := services.FetchProjectsContent()
+	projects, err := services.FetchProjectsContent(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
+	// Pre-rendered HTML is opt-in, raw markdown stays the default
+	if c.QueryParam("render") == "html" {
+		for i := range projects {
+			if err := services.RenderProject(&projects[i]); err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			}
+		}
+	}
+
 	return c.JSON(http.StatusOK, projects)
 }
+
+func getProject(c echo.Context) error {
+	project, redirected, err := services.GetProjectBySlug(c.Request().Context(), c.Param("slug"))
+	if errors.Is(err, services.ErrProjectNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	// Old slugs permanently point to the current one
+	if redirected {
+		location := "/api/projects/" + project.Slug
+		if query := c.QueryString(); query != "" {
+			location += "?" + query
+		}
+		return c.Redirect(http.StatusMovedPermanently, location)
+	}
+
+	if c.QueryParam("render") == "html" {
+		if err := services.RenderProject(&project); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+	}
+
+	return c.JSON(http.StatusOK, project)
+}
+
+func getStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, services.GetCommitStats())
+}
+
+func getStatsHeatmap(c echo.Context) error {
+	stats := services.GetCommitStats()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"heatmap":      stats.Heatmap,
+		"generated_at": stats.GeneratedAt,
+	})
+}
+
+func getStatsRepos(c echo.Context) error {
+	stats := services.GetCommitStats()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"repos":             stats.Repos,
+		"most_active_repos": stats.MostActiveRepos,
+		"generated_at":      stats.GeneratedAt,
+	})
+}
+
+func getStatsDistribution(c echo.Context) error {
+	stats := services.GetCommitStats()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"distribution": stats.Distribution,
+		"generated_at": stats.GeneratedAt,
+	})
+}
+
+func getStatsStreaks(c echo.Context) error {
+	stats := services.GetCommitStats()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"streaks":      stats.Streaks,
+		"generated_at": stats.GeneratedAt,
+	})
+}
+
+func getStatsLanguages(c echo.Context) error {
+	stats := services.GetCommitStats()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"languages":        stats.Languages,
+		"enriched_commits": stats.EnrichedCommits,
+		"total_commits":    stats.TotalCommits,
+		"generated_at":     stats.GeneratedAt,
+	})
+}
+
+func githubWebhook(c echo.Context) error {
+	eventType, err := services.HandleGitHubWebhook(c.Request())
+	switch {
+	case errors.Is(err, services.ErrWebhookDisabled):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidSignature):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPayload):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"event": eventType, "status": "processed"})
+}
@@ -1,43 +1,348 @@
 package config
 
 import (
+	"bytes"
 	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// ForgeAccount is an account on a code forge commits are synced from, besides the GitHub
+// accounts of GITHUB_TOKEN and GITHUB_ACCOUNTS, which are crawled branch by branch
+type ForgeAccount struct {
+	// Forge is one of github, gitlab, gitea, forgejo or codeberg
+	Forge string `json:"forge" yaml:"forge" toml:"forge"`
+	// URL is the root of the instance, it defaults to the public instance of the forge
+	URL   string `json:"url" yaml:"url" toml:"url"`
+	Token string `json:"token" yaml:"token" toml:"token"`
+	// Author restricts the account to commits authored by this name, email or login
+	Author string `json:"author" yaml:"author" toml:"author"`
+}
+
+// GitHubAccount is a github.com account crawled like the one of GITHUB_TOKEN, for example a
+// work identity with access to organization repositories
+type GitHubAccount struct {
+	// Name attributes the commits of the account
+	Name  string `json:"name" yaml:"name" toml:"name"`
+	Token string `json:"token" yaml:"token" toml:"token"`
+	// Emails are the commit author emails of the account, besides its login
+	Emails []string `json:"emails" yaml:"emails" toml:"emails"`
+	// Affiliation lists the repositories to crawl: owner, collaborator or organization_member,
+	// comma-separated
+	Affiliation string `json:"affiliation" yaml:"affiliation" toml:"affiliation"`
+}
+
+// RepoRules selects the repositories commits are synced from and overrides their visibility.
+// Patterns are globs matched against owner/name, or against the name alone when they have no
+// slash, or regular expressions matched against owner/name when prefixed with "re:".
+type RepoRules struct {
+	// Include keeps only the matching repositories when it is not empty
+	Include []string `json:"include" yaml:"include" toml:"include"`
+	// Exclude drops the matching repositories, even included ones
+	Exclude         []string       `json:"exclude" yaml:"exclude" toml:"exclude"`
+	ExcludeArchived bool           `json:"exclude_archived" yaml:"exclude_archived" toml:"exclude_archived"`
+	ExcludeForks    bool           `json:"exclude_forks" yaml:"exclude_forks" toml:"exclude_forks"`
+	Overrides       []RepoOverride `json:"overrides" yaml:"overrides" toml:"overrides"`
+}
+
+// RepoOverride changes how the commits of the matching repositories are shown, the first
+// matching override applies
+type RepoOverride struct {
+	Repo string `json:"repo" yaml:"repo" toml:"repo"`
+	// Private replaces the visibility reported by the forge when set
+	Private *bool `json:"private" yaml:"private" toml:"private"`
+	// ShowName keeps the repository name of private commits readable
+	ShowName bool `json:"show_name" yaml:"show_name" toml:"show_name"`
+}
+
+// Default instances of forges that have a public one
+var defaultForgeURLs = map[string]string{
+	"github":   "https://api.github.com",
+	"gitlab":   "https://gitlab.com",
+	"codeberg": "https://codeberg.org",
+}
+
+// redactedSecret replaces secrets in the printed configuration
+const redactedSecret = "REDACTED"
+
+// Config is read from layers, each one overriding the previous: the defaults, the
+// configuration file, the .env file, the environment and the command line flags. File keys
+// are the environment variable names in lowercase.
 type Config struct {
-	AllowedOrigins []string
-	Port           string
-	GitHubToken    string
+	AllowedOrigins  []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	Port            string   `yaml:"port" toml:"port"`
+	GitHubToken     string   `yaml:"github_token" toml:"github_token"`
+	CacheBackend    string   `yaml:"cache_backend" toml:"cache_backend"`
+	CachePath       string   `yaml:"cache_path" toml:"cache_path"`
+	ActivitySources []string `yaml:"activity_sources" toml:"activity_sources"`
+
+	CORSAllowedMethods []string `yaml:"cors_allowed_methods" toml:"cors_allowed_methods"`
+	CORSAllowedHeaders []string `yaml:"cors_allowed_headers" toml:"cors_allowed_headers"`
+	// ShutdownTimeout bounds the time in-flight requests get to complete on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// HTTPTimeout bounds the requests to the APIs of forges and Mastodon
+	HTTPTimeout time.Duration `yaml:"http_timeout" toml:"http_timeout"`
+
+	// GitHubAccountName, GitHubAuthorEmails and GitHubAffiliation describe the GITHUB_TOKEN account
+	GitHubAccountName  string          `yaml:"github_account_name" toml:"github_account_name"`
+	GitHubAuthorEmails []string        `yaml:"github_author_emails" toml:"github_author_emails"`
+	GitHubAffiliation  string          `yaml:"github_affiliation" toml:"github_affiliation"`
+	GitHubAccounts     []GitHubAccount `yaml:"github_accounts" toml:"github_accounts"`
+	// VersionOwner and VersionRepo locate the repository whose latest release is the version
+	VersionOwner string `yaml:"version_owner" toml:"version_owner"`
+	VersionRepo  string `yaml:"version_repo" toml:"version_repo"`
+
+	GitHubWebhookSecret string `yaml:"github_webhook_secret" toml:"github_webhook_secret"`
+	// PollInterval defaults to 1m, or 15m when webhooks deliver pushes
+	PollInterval         time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	ProjectPollInterval  time.Duration `yaml:"project_poll_interval" toml:"project_poll_interval"`
+	ActivityPollInterval time.Duration `yaml:"activity_poll_interval" toml:"activity_poll_interval"`
+	// ProjectRefreshTimeout and ActivityRefreshTimeout bound one refresh of the project
+	// content and of an activity source
+	ProjectRefreshTimeout  time.Duration `yaml:"project_refresh_timeout" toml:"project_refresh_timeout"`
+	ActivityRefreshTimeout time.Duration `yaml:"activity_refresh_timeout" toml:"activity_refresh_timeout"`
+
+	ObfuscationSecret string `yaml:"obfuscation_secret" toml:"obfuscation_secret"`
+	ObfuscationMode   string `yaml:"obfuscation_mode" toml:"obfuscation_mode"`
+
+	EnrichCommits     bool `yaml:"enrich_commits" toml:"enrich_commits"`
+	EnrichmentWorkers int  `yaml:"enrichment_workers" toml:"enrichment_workers"`
+	// EnrichmentTimeout bounds an enrichment cycle, the remaining commits wait for the next one
+	EnrichmentTimeout time.Duration `yaml:"enrichment_timeout" toml:"enrichment_timeout"`
+
+	CrawlConcurrency int           `yaml:"crawl_concurrency" toml:"crawl_concurrency"`
+	CrawlRepoTimeout time.Duration `yaml:"crawl_repo_timeout" toml:"crawl_repo_timeout"`
+	// Branches are globs of the branches synced besides the default one, "*" for all
+	Branches []string `yaml:"branches" toml:"branches"`
+
+	ForgeAccounts []ForgeAccount `yaml:"forge_accounts" toml:"forge_accounts"`
+
+	// LocalRepoDirs are directories scanned for git repositories never pushed to a forge
+	LocalRepoDirs     []string `yaml:"local_repos_dirs" toml:"local_repos_dirs"`
+	LocalAuthorEmails []string `yaml:"local_author_emails" toml:"local_author_emails"`
+	LocalReposPrivate bool     `yaml:"local_repos_private" toml:"local_repos_private"`
+
+	RepoRules RepoRules `yaml:"repo_rules" toml:"repo_rules"`
+
+	ContentSource string `yaml:"content_source" toml:"content_source"`
+	// ContentOwner, ContentRepo and ContentPath locate the projects of the github content source
+	ContentOwner string `yaml:"content_owner" toml:"content_owner"`
+	ContentRepo  string `yaml:"content_repo" toml:"content_repo"`
+	ContentPath  string `yaml:"content_path" toml:"content_path"`
+	ContentDir   string `yaml:"content_dir" toml:"content_dir"`
+
+	MastodonInstance string `yaml:"mastodon_instance" toml:"mastodon_instance"`
+	MastodonAccount  string `yaml:"mastodon_account" toml:"mastodon_account"`
+	MastodonToken    string `yaml:"mastodon_token" toml:"mastodon_token"`
+
+	// PrintConfig asks to print the configuration instead of serving
+	PrintConfig bool `yaml:"-" toml:"-"`
 }
 
-func Load() (*Config, error) {
-	err := godotenv.Load()
-	if err != nil {
+// Defaults returns the configuration used for settings no layer sets, services run with
+// it until they are initialized with the loaded one
+func Defaults() *Config {
+	return &Config{
+		Port:            ":5432",
+		CacheBackend:    "memory",
+		CachePath:       "commits.db",
+		ActivitySources: []string{"commits"},
+
+		CORSAllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		CORSAllowedHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		ShutdownTimeout:    10 * time.Second,
+		HTTPTimeout:        30 * time.Second,
+
+		GitHubAffiliation: "owner",
+		VersionOwner:      "bnema",
+		VersionRepo:       "portfolio-monorepo",
+
+		ProjectPollInterval:  time.Minute,
+		ActivityPollInterval: time.Minute,
+
+		ProjectRefreshTimeout:  2 * time.Minute,
+		ActivityRefreshTimeout: 2 * time.Minute,
+
+		ObfuscationMode: "length",
+
+		// Enrichment costs one API call per commit, it is opt-in
+		EnrichmentWorkers: 4,
+		EnrichmentTimeout: 5 * time.Minute,
+
+		CrawlConcurrency: 4,
+		CrawlRepoTimeout: 2 * time.Minute,
+
+		// Local repositories are usually unpublished work, they are private unless told otherwise
+		LocalReposPrivate: true,
+
+		ContentSource: "github",
+		ContentOwner:  "bnema",
+		ContentRepo:   "portfolio-mono",
+		ContentPath:   "content/projects",
+		ContentDir:    "../content/projects",
+	}
+}
+
+// Load reads the configuration from the command line arguments, the configuration file set
+// with --config or CONFIG_FILE, the optional .env file and the environment. Every problem
+// found is reported at once.
+func Load(args []string) (*Config, error) {
+	cfg := Defaults()
+	settings := cfg.settings()
+
+	// Flags are applied last, so they are only collected while parsing
+	flags := flag.NewFlagSet("portfolio-backend", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path of a YAML or TOML configuration file")
+	flags.BoolVar(&cfg.PrintConfig, "print-config", false, "print the configuration with secrets redacted and exit")
+	var flagValues []settingValue
+	for _, s := range settings {
+		flags.Func(s.flagName(), s.usage, func(value string) error {
+			flagValues = append(flagValues, settingValue{setting: s, value: value})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
 		return nil, err
 	}
 
-	allowedOrigins := strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",")
-	if len(allowedOrigins) == 0 {
-		return nil, errors.New("ALLOWED_ORIGINS is required in .env file")
+	// A missing .env is fine, the environment may come from the container runtime
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read .env: %w", err)
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = ":5432"
+	var errs []error
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	if githubToken == "" {
-		return nil, errors.New("GITHUB_TOKEN is required in .env file")
+	// Empty variables are taken as unset
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s %w", s.env, err))
+			}
+		}
+	}
+	for _, v := range flagValues {
+		if err := v.setting.set(v.value); err != nil {
+			errs = append(errs, fmt.Errorf("--%s %w", v.setting.flagName(), err))
+		}
 	}
 
-	return &Config{
-		AllowedOrigins: allowedOrigins,
-		Port:           port,
-		GitHubToken:    githubToken,
-	}, nil
+	cfg.applyDefaults()
+	if err := cfg.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile reads a YAML or TOML configuration file, chosen by its extension, over the
+// current configuration. Unknown keys are rejected so typos do not go unnoticed.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.NewDecoder(bytes.NewReader(data)).Decode(c)
+		if err != nil {
+			return fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("invalid configuration file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("configuration file %s must be .yaml, .yml or .toml, not %q", path, ext)
+	}
+	return nil
+}
+
+// applyDefaults fills the settings whose default depends on other settings
+func (c *Config) applyDefaults() {
+	// Webhooks deliver pushes instantly, polling is only a fallback then
+	if c.PollInterval == 0 {
+		c.PollInterval = time.Minute
+		if c.GitHubWebhookSecret != "" {
+			c.PollInterval = 15 * time.Minute
+		}
+	}
+
+	if c.GitHubAffiliation == "" {
+		c.GitHubAffiliation = "owner"
+	}
+	for i := range c.GitHubAccounts {
+		if c.GitHubAccounts[i].Affiliation == "" {
+			c.GitHubAccounts[i].Affiliation = "owner"
+		}
+	}
+
+	for i, account := range c.ForgeAccounts {
+		account.Forge = strings.ToLower(strings.TrimSpace(account.Forge))
+		if account.URL == "" {
+			account.URL = defaultForgeURLs[account.Forge]
+		}
+		account.URL = strings.TrimSuffix(account.URL, "/")
+		c.ForgeAccounts[i] = account
+	}
+}
+
+// Redacted returns a copy of the configuration with its secrets replaced, so it can be shown
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redact := func(secret *string) {
+		if *secret != "" {
+			*secret = redactedSecret
+		}
+	}
+	redact(&redacted.GitHubToken)
+	redact(&redacted.GitHubWebhookSecret)
+	redact(&redacted.ObfuscationSecret)
+	redact(&redacted.MastodonToken)
+
+	redacted.GitHubAccounts = append([]GitHubAccount(nil), c.GitHubAccounts...)
+	for i := range redacted.GitHubAccounts {
+		redact(&redacted.GitHubAccounts[i].Token)
+	}
+	redacted.ForgeAccounts = append([]ForgeAccount(nil), c.ForgeAccounts...)
+	for i := range redacted.ForgeAccounts {
+		redact(&redacted.ForgeAccounts[i].Token)
+	}
+	return &redacted
+}
+
+// Print writes the configuration as YAML with its secrets redacted. The output is a valid
+// configuration file once the redacted secrets are filled in again, Load rejects the placeholder.
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
 }
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write configuration file: %v", err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+allowed_origins: [https://example.com]
+github_token: from-file
+poll_interval: 5m
+crawl_concurrency: 2
+content_owner: someone
+repo_rules:
+  exclude: ["*-sandbox"]
+`)
+	t.Setenv("CRAWL_CONCURRENCY", "6")
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "hook")
+	t.Setenv("CORS_ALLOWED_METHODS", "GET, OPTIONS")
+	t.Setenv("HTTP_TIMEOUT", "10s")
+
+	cfg, err := Load([]string{"--config", path, "--crawl-concurrency", "8"})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+
+	if cfg.GitHubToken != "from-file" || cfg.ContentOwner != "someone" || cfg.RepoRules.Exclude[0] != "*-sandbox" {
+		t.Errorf("Expected the file to override the defaults, got %+v", cfg)
+	}
+	if cfg.CrawlConcurrency != 8 {
+		t.Errorf("Expected flags to override the environment, got %d", cfg.CrawlConcurrency)
+	}
+	if strings.Join(cfg.CORSAllowedMethods, ",") != "GET,OPTIONS" || cfg.HTTPTimeout != 10*time.Second {
+		t.Errorf("Expected the environment to override the defaults, got %v and %s", cfg.CORSAllowedMethods, cfg.HTTPTimeout)
+	}
+	// An explicit interval wins over the webhook default
+	if cfg.PollInterval != 5*time.Minute {
+		t.Errorf("Expected the configured poll interval, got %s", cfg.PollInterval)
+	}
+	if cfg.ContentRepo != "portfolio-mono" || cfg.CacheBackend != "memory" || cfg.VersionRepo != "portfolio-monorepo" || cfg.EnrichmentTimeout != 5*time.Minute || !cfg.LocalReposPrivate {
+		t.Errorf("Expected defaults for unset settings, got %+v", cfg)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+allowed_origins = ["https://example.com"]
+content_source = "local"
+poll_interval = "30s"
+
+[[forge_accounts]]
+forge = "Codeberg"
+token = "secret"
+`)
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if cfg.PollInterval != 30*time.Second {
+		t.Errorf("Expected a 30s poll interval, got %s", cfg.PollInterval)
+	}
+	if len(cfg.ForgeAccounts) != 1 || cfg.ForgeAccounts[0].Forge != "codeberg" || cfg.ForgeAccounts[0].URL != "https://codeberg.org" {
+		t.Errorf("Expected the forge account to get its default instance, got %+v", cfg.ForgeAccounts)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "unknown_key: true\n")
+	t.Setenv("ALLOWED_ORIGINS", " , ")
+	t.Setenv("OBFUSCATION_MODE", "blur")
+	t.Setenv("ENRICH_COMMITS", "maybe")
+
+	_, err := Load([]string{"--config", path, "--crawl-repo-timeout", "-1m", "--cors-allowed-methods", "GET,FETCH"})
+	if err == nil {
+		t.Fatal("Expected Load to fail")
+	}
+	for _, problem := range []string{
+		"unknown_key",
+		"ALLOWED_ORIGINS is required",
+		"GITHUB_TOKEN is required",
+		"OBFUSCATION_MODE must be one of",
+		"ENRICH_COMMITS must be a boolean",
+		"CRAWL_REPO_TIMEOUT must be a positive duration",
+		`unknown method "FETCH"`,
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected the error to report %q, got:\n%v", problem, err)
+		}
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := Defaults()
+	cfg.GitHubToken = "ghp_secret"
+	cfg.MastodonToken = "mastodon_secret"
+	cfg.GitHubAccounts = []GitHubAccount{{Name: "work", Token: "work_secret"}}
+	cfg.ForgeAccounts = []ForgeAccount{{Forge: "gitlab", URL: "https://gitlab.com", Token: "glpat_secret"}}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print returned an error: %v", err)
+	}
+	for _, secret := range []string{"ghp_secret", "mastodon_secret", "work_secret", "glpat_secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("Expected %s to be redacted, got:\n%s", secret, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "name: work") || strings.Count(out.String(), redactedSecret) != 4 {
+		t.Errorf("Expected the redacted configuration, got:\n%s", out.String())
+	}
+	if cfg.GitHubAccounts[0].Token != "work_secret" {
+		t.Error("Expected the configuration itself to keep its secrets")
+	}
+}
+
+func TestLoadRejectsRedactedSecrets(t *testing.T) {
+	cfg := Defaults()
+	cfg.AllowedOrigins = []string{"https://example.com"}
+	cfg.GitHubToken = "ghp_secret"
+	cfg.GitHubAccounts = []GitHubAccount{{Name: "work", Token: "work_secret", Affiliation: "owner"}}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print returned an error: %v", err)
+	}
+	path := writeConfigFile(t, "printed.yaml", out.String())
+
+	_, err := Load([]string{"--config", path})
+	if err == nil {
+		t.Fatal("Expected Load to reject the printed configuration")
+	}
+	for _, problem := range []string{"GITHUB_TOKEN is still the REDACTED placeholder", "GITHUB_ACCOUNTS token of work is still"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected the error to report %q, got:\n%v", problem, err)
+		}
+	}
+}
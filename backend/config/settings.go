@@ -0,0 +1,154 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a configuration value that can be set from the environment or a flag. The
+// flag name is the variable name in lowercase with dashes, such as --poll-interval.
+type setting struct {
+	env   string
+	usage string
+	set   func(value string) error
+}
+
+type settingValue struct {
+	setting setting
+	value   string
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+// settings lists every setting of the configuration, bound to its fields
+func (c *Config) settings() []setting {
+	return []setting{
+		{"ALLOWED_ORIGINS", "origins allowed to call the API, comma-separated", listValue(&c.AllowedOrigins)},
+		{"PORT", "address the server listens on, such as :5432", stringValue(&c.Port)},
+		{"GITHUB_TOKEN", "GitHub token used for commits and project content", stringValue(&c.GitHubToken)},
+		{"CACHE_BACKEND", "commit cache store, memory or bolt", stringValue(&c.CacheBackend)},
+		{"CACHE_PATH", "path of the bolt commit cache", stringValue(&c.CachePath)},
+		{"ACTIVITY_SOURCES", "activity sources, comma-separated", listValue(&c.ActivitySources)},
+
+		{"CORS_ALLOWED_METHODS", "HTTP methods allowed by CORS, comma-separated", listValue(&c.CORSAllowedMethods)},
+		{"CORS_ALLOWED_HEADERS", "request headers allowed by CORS, comma-separated", listValue(&c.CORSAllowedHeaders)},
+		{"SHUTDOWN_TIMEOUT", "time given to in-flight requests on shutdown", durationValue(&c.ShutdownTimeout)},
+		{"HTTP_TIMEOUT", "timeout of the requests to forge and Mastodon APIs", durationValue(&c.HTTPTimeout)},
+
+		{"GITHUB_ACCOUNT_NAME", "name attributing the commits of the GITHUB_TOKEN account", stringValue(&c.GitHubAccountName)},
+		{"GITHUB_AUTHOR_EMAILS", "commit emails of the GITHUB_TOKEN account, comma-separated", listValue(&c.GitHubAuthorEmails)},
+		{"GITHUB_AFFILIATION", "repositories of the GITHUB_TOKEN account to crawl", stringValue(&c.GitHubAffiliation)},
+		{"GITHUB_ACCOUNTS", "other GitHub accounts, as a JSON list", jsonValue(&c.GitHubAccounts, "a JSON list of accounts")},
+		{"VERSION_OWNER", "owner of the repository whose latest release is the version", stringValue(&c.VersionOwner)},
+		{"VERSION_REPO", "name of the repository whose latest release is the version", stringValue(&c.VersionRepo)},
+
+		{"GITHUB_WEBHOOK_SECRET", "secret verifying GitHub webhook deliveries", stringValue(&c.GitHubWebhookSecret)},
+		{"POLL_INTERVAL", "interval between commit syncs", durationValue(&c.PollInterval)},
+		{"PROJECT_POLL_INTERVAL", "interval between project content refreshes", durationValue(&c.ProjectPollInterval)},
+		{"ACTIVITY_POLL_INTERVAL", "interval between activity refreshes", durationValue(&c.ActivityPollInterval)},
+		{"PROJECT_REFRESH_TIMEOUT", "time allowed to refresh the project content", durationValue(&c.ProjectRefreshTimeout)},
+		{"ACTIVITY_REFRESH_TIMEOUT", "time allowed to refresh one activity source", durationValue(&c.ActivityRefreshTimeout)},
+
+		{"OBFUSCATION_SECRET", "secret deriving the IDs of private commits", stringValue(&c.ObfuscationSecret)},
+		{"OBFUSCATION_MODE", "redaction of private commits, glyph, length, placeholder or repo", stringValue(&c.ObfuscationMode)},
+
+		{"ENRICH_COMMITS", "add sizes and languages to commits", boolValue(&c.EnrichCommits)},
+		{"ENRICHMENT_WORKERS", "number of concurrent enrichment requests", intValue(&c.EnrichmentWorkers)},
+		{"ENRICHMENT_TIMEOUT", "time allowed to one enrichment cycle", durationValue(&c.EnrichmentTimeout)},
+
+		{"CRAWL_CONCURRENCY", "number of repositories crawled concurrently", intValue(&c.CrawlConcurrency)},
+		{"CRAWL_REPO_TIMEOUT", "time allowed to crawl one repository", durationValue(&c.CrawlRepoTimeout)},
+		{"BRANCHES", "globs of the branches synced besides the default one, comma-separated", listValue(&c.Branches)},
+
+		{"FORGE_ACCOUNTS", "GitLab, Gitea and other forge accounts, as a JSON list", jsonValue(&c.ForgeAccounts, "a JSON list of accounts")},
+
+		{"LOCAL_REPOS_DIRS", "directories scanned for git repositories, comma-separated", listValue(&c.LocalRepoDirs)},
+		{"LOCAL_AUTHOR_EMAILS", "commit emails kept from local repositories, comma-separated", listValue(&c.LocalAuthorEmails)},
+		{"LOCAL_REPOS_PRIVATE", "treat local repositories as private", boolValue(&c.LocalReposPrivate)},
+
+		{"REPO_RULES", "repository include, exclude and visibility rules, as a JSON object", jsonValue(&c.RepoRules, "a JSON object")},
+
+		{"CONTENT_SOURCE", "where project content is read from, github or local", stringValue(&c.ContentSource)},
+		{"CONTENT_OWNER", "owner of the project content repository", stringValue(&c.ContentOwner)},
+		{"CONTENT_REPO", "name of the project content repository", stringValue(&c.ContentRepo)},
+		{"CONTENT_PATH", "directory of the projects in the content repository", stringValue(&c.ContentPath)},
+		{"CONTENT_DIR", "directory of the projects for the local content source", stringValue(&c.ContentDir)},
+
+		{"MASTODON_INSTANCE", "Mastodon instance of the activity source", stringValue(&c.MastodonInstance)},
+		{"MASTODON_ACCOUNT", "Mastodon account of the activity source", stringValue(&c.MastodonAccount)},
+		{"MASTODON_TOKEN", "Mastodon access token", stringValue(&c.MastodonToken)},
+	}
+}
+
+func stringValue(p *string) func(string) error {
+	return func(value string) error {
+		*p = value
+		return nil
+	}
+}
+
+// listValue splits a comma-separated list, dropping empty entries
+func listValue(p *[]string) func(string) error {
+	return func(value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+		return nil
+	}
+}
+
+func boolValue(p *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		*p = b
+		return nil
+	}
+}
+
+func intValue(p *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		*p = n
+		return nil
+	}
+}
+
+func durationValue(p *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration such as 5m")
+		}
+		*p = d
+		return nil
+	}
+}
+
+// jsonValue decodes structured settings, which do not fit in a plain variable. The value
+// replaces the one of lower layers rather than being merged into it.
+func jsonValue[T any](p *T, description string) func(string) error {
+	return func(value string) error {
+		var v T
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return fmt.Errorf("must be %s: %w", description, err)
+		}
+		*p = v
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// validate lists every problem found in the configuration
+func (c *Config) validate() error {
+	var errs []error
+	positiveDuration := func(name string, d time.Duration) {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration such as 5m", name))
+		}
+	}
+	positiveNumber := func(name string, n int) {
+		if n < 1 {
+			errs = append(errs, fmt.Errorf("%s must be a positive number", name))
+		}
+	}
+	// A file written by --print-config keeps the placeholder until the secrets are filled in
+	notRedacted := func(name, secret string) {
+		if secret == redactedSecret {
+			errs = append(errs, fmt.Errorf("%s is still the %s placeholder of --print-config", name, redactedSecret))
+		}
+	}
+
+	if len(c.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("ALLOWED_ORIGINS is required"))
+	}
+	if c.Port == "" {
+		errs = append(errs, errors.New("PORT must not be empty"))
+	}
+	for _, method := range c.CORSAllowedMethods {
+		switch method {
+		case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS":
+		default:
+			errs = append(errs, fmt.Errorf("CORS_ALLOWED_METHODS has an unknown method %q", method))
+		}
+	}
+	positiveDuration("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	positiveDuration("HTTP_TIMEOUT", c.HTTPTimeout)
+
+	switch c.ContentSource {
+	case "github":
+		// Only the GitHub content source strictly needs a token, commits are skipped without one
+		if c.GitHubToken == "" {
+			errs = append(errs, errors.New("GITHUB_TOKEN is required by the github content source"))
+		}
+		if c.ContentOwner == "" || c.ContentRepo == "" || c.ContentPath == "" {
+			errs = append(errs, errors.New("CONTENT_OWNER, CONTENT_REPO and CONTENT_PATH are required by the github content source"))
+		}
+	case "local":
+		if c.ContentDir == "" {
+			errs = append(errs, errors.New("CONTENT_DIR is required by the local content source"))
+		}
+	default:
+		errs = append(errs, errors.New("CONTENT_SOURCE must be either github or local"))
+	}
+
+	if !validAffiliation(c.GitHubAffiliation) {
+		errs = append(errs, errors.New("GITHUB_AFFILIATION must list owner, collaborator or organization_member, comma-separated"))
+	}
+	if c.VersionOwner == "" || c.VersionRepo == "" {
+		errs = append(errs, errors.New("VERSION_OWNER and VERSION_REPO must not be empty"))
+	}
+	notRedacted("GITHUB_TOKEN", c.GitHubToken)
+	notRedacted("GITHUB_WEBHOOK_SECRET", c.GitHubWebhookSecret)
+	notRedacted("OBFUSCATION_SECRET", c.ObfuscationSecret)
+	notRedacted("MASTODON_TOKEN", c.MastodonToken)
+	if len(c.GitHubAccounts) > 0 && c.GitHubToken == "" {
+		errs = append(errs, errors.New("GITHUB_ACCOUNTS needs GITHUB_TOKEN to be set as well"))
+	}
+	accountNames := map[string]bool{c.GitHubAccountName: true}
+	for _, account := range c.GitHubAccounts {
+		if account.Name == "" || account.Token == "" {
+			errs = append(errs, errors.New("every account of GITHUB_ACCOUNTS needs a name and a token"))
+			continue
+		}
+		if accountNames[account.Name] {
+			errs = append(errs, fmt.Errorf("GITHUB_ACCOUNTS has the name %q more than once", account.Name))
+		}
+		accountNames[account.Name] = true
+		notRedacted("GITHUB_ACCOUNTS token of "+account.Name, account.Token)
+		if !validAffiliation(account.Affiliation) {
+			errs = append(errs, fmt.Errorf("GITHUB_ACCOUNTS has an invalid affiliation %q for %s", account.Affiliation, account.Name))
+		}
+	}
+
+	if c.CacheBackend != "memory" && c.CacheBackend != "bolt" {
+		errs = append(errs, errors.New("CACHE_BACKEND must be either memory or bolt"))
+	}
+
+	positiveDuration("POLL_INTERVAL", c.PollInterval)
+	positiveDuration("PROJECT_POLL_INTERVAL", c.ProjectPollInterval)
+	positiveDuration("ACTIVITY_POLL_INTERVAL", c.ActivityPollInterval)
+	positiveDuration("PROJECT_REFRESH_TIMEOUT", c.ProjectRefreshTimeout)
+	positiveDuration("ACTIVITY_REFRESH_TIMEOUT", c.ActivityRefreshTimeout)
+
+	switch c.ObfuscationMode {
+	case "glyph", "length", "placeholder", "repo":
+	default:
+		errs = append(errs, errors.New("OBFUSCATION_MODE must be one of glyph, length, placeholder or repo"))
+	}
+
+	positiveNumber("ENRICHMENT_WORKERS", c.EnrichmentWorkers)
+	positiveDuration("ENRICHMENT_TIMEOUT", c.EnrichmentTimeout)
+	positiveNumber("CRAWL_CONCURRENCY", c.CrawlConcurrency)
+	positiveDuration("CRAWL_REPO_TIMEOUT", c.CrawlRepoTimeout)
+
+	for _, branch := range c.Branches {
+		if _, err := path.Match(branch, ""); err != nil {
+			errs = append(errs, fmt.Errorf("BRANCHES has an invalid pattern %q", branch))
+		}
+	}
+
+	for _, account := range c.ForgeAccounts {
+		switch account.Forge {
+		case "github", "gitlab", "gitea", "forgejo", "codeberg":
+		default:
+			errs = append(errs, fmt.Errorf("FORGE_ACCOUNTS has an unknown forge %q, expected github, gitlab, gitea, forgejo or codeberg", account.Forge))
+			continue
+		}
+		if u, err := url.Parse(account.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("FORGE_ACCOUNTS needs the URL of the %s instance", account.Forge))
+		}
+		notRedacted("FORGE_ACCOUNTS token of "+account.Forge, account.Token)
+	}
+
+	patterns := append(append([]string{}, c.RepoRules.Include...), c.RepoRules.Exclude...)
+	for _, override := range c.RepoRules.Overrides {
+		patterns = append(patterns, override.Repo)
+	}
+	for _, pattern := range patterns {
+		if err := ValidateRepoPattern(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("REPO_RULES has an invalid pattern: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validAffiliation reports whether a comma-separated affiliation only lists the
+// affiliations of the GitHub API
+func validAffiliation(affiliation string) bool {
+	for _, value := range strings.Split(affiliation, ",") {
+		switch strings.TrimSpace(value) {
+		case "owner", "collaborator", "organization_member":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateRepoPattern reports whether a repository pattern of RepoRules is a valid glob or,
+// with the re: prefix, a valid regular expression
+func ValidateRepoPattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("empty pattern")
+	}
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("%q: %w", pattern, err)
+		}
+		return nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%q: %w", pattern, err)
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,7 +12,6 @@ import (
 	"portfolio-backend/services"
 	"sync"
 	"syscall"
-	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/labstack/echo/v4"
@@ -19,14 +20,66 @@ import (
 
 func main() {
 	// Load and validate configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatal("Error loading configuration", "error", err)
 	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal("Error printing configuration", "error", err)
+		}
+		return
+	}
 
 	// Initialize GitHub client
 	services.InitGitHubClient(cfg)
 
+	// Repositories are crawled concurrently on cold start
+	services.InitCrawler(cfg)
+
+	// Private commits are redacted with a server side secret
+	services.InitObfuscation(cfg)
+
+	// Repository rules decide which repositories are synced and how private they are
+	if err := services.InitRepoRules(cfg); err != nil {
+		log.Fatal("Error initializing repository rules", "error", err)
+	}
+
+	// Restore the commit cache from its store
+	if err := services.InitCommitCache(cfg); err != nil {
+		log.Fatal("Error initializing commit cache", "error", err)
+	}
+	defer func() {
+		if err := services.CloseCommitCache(); err != nil {
+			log.Error("Error closing commit cache", "error", err)
+		}
+	}()
+
+	// Register the GitLab, Gitea and other forge accounts commits are synced from, resuming
+	// from their last sync
+	if err := services.InitCommitProviders(cfg); err != nil {
+		log.Fatal("Error initializing commit providers", "error", err)
+	}
+
+	// Optionally add commit sizes and languages to cached commits
+	services.InitEnrichment(cfg)
+
+	// Select where project content is read from
+	if err := services.InitContentProvider(cfg); err != nil {
+		log.Fatal("Error initializing content provider", "error", err)
+	}
+
+	// Verify GitHub webhook deliveries with the configured secret
+	services.InitGitHubWebhook(cfg)
+
+	// Register the enabled activity sources
+	if err := services.InitActivitySources(cfg); err != nil {
+		log.Fatal("Error initializing activity sources", "error", err)
+	}
+
 	// Create a WaitGroup to manage background tasks
 	var wg sync.WaitGroup
 
@@ -34,7 +87,21 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		services.StartCacheUpdateScheduler()
+		services.StartCacheUpdateScheduler(cfg.PollInterval)
+	}()
+
+	// Start project cache scheduler in the background
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		services.StartProjectCacheScheduler(cfg.ProjectPollInterval)
+	}()
+
+	// Start activity update scheduler in the background
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		services.StartActivityUpdateScheduler(cfg.ActivityPollInterval)
 	}()
 
 	e := echo.New()
@@ -48,8 +115,8 @@ func main() {
 	// CORS
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     cfg.AllowedOrigins,
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:     cfg.CORSAllowedHeaders,
+		AllowMethods:     cfg.CORSAllowedMethods,
 		AllowCredentials: true,
 	}))
 
@@ -82,7 +149,7 @@ func main() {
 	log.Warn("Received interrupt, shutting down gracefully")
 
 	// Shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 	if err := e.Shutdown(shutdownCtx); err != nil {
 		log.Error("Error during server shutdown" + err.Error())
@@ -7,6 +7,30 @@ type Commit struct {
 	Timestamp string `json:"timestamp"`
 	URL       string `json:"url"`
 	IsPrivate bool   `json:"is_private"`
+	// Forge is the kind of forge the commit comes from, such as github or gitlab
+	Forge string `json:"forge,omitempty"`
+	// Account is the configured identity the commit is attributed to
+	Account string `json:"account,omitempty"`
+	// Branches lists the branches the commit was found on, hidden for private commits
+	Branches []string `json:"branches,omitempty"`
+
+	// Conventional Commits fields parsed from the message, Type is empty for other messages
+	Type     string `json:"type,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	Breaking bool   `json:"breaking"`
+	Subject  string `json:"subject"`
+	Body     string `json:"body,omitempty"`
+
+	// Filled by the optional enrichment pipeline
+	Changes  *CommitChanges `json:"changes,omitempty"`
+	Language string         `json:"language,omitempty"`
+}
+
+// CommitChanges is the size of a commit, as reported by the GitHub API
+type CommitChanges struct {
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	FilesChanged int `json:"files_changed"`
 }
 
 type Tweet struct {
@@ -21,8 +45,168 @@ type Tweet struct {
 }
 
 type Project struct {
-	Title         string `json:"title"`
-	Slug          string `json:"slug"`
-	Content       string `json:"content"`
-	ProjectOrigin string `json:"project_origin"`
+	Title         string   `json:"title"`
+	Slug          string   `json:"slug"`
+	Aliases       []string `json:"aliases,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	TechStack     []string `json:"tech_stack,omitempty"`
+	RepoURL       string   `json:"repo_url,omitempty"`
+	DemoURL       string   `json:"demo_url,omitempty"`
+	CoverImage    string   `json:"cover_image,omitempty"`
+	Status        string   `json:"status,omitempty"`
+	Order         int      `json:"order"`
+	Date          string   `json:"date,omitempty"`
+	Content       string   `json:"content"`
+	ProjectOrigin string   `json:"project_origin"`
+
+	// Only set when the project is rendered server side
+	HTML    string     `json:"html,omitempty"`
+	TOC     []TOCEntry `json:"toc,omitempty"`
+	Excerpt string     `json:"excerpt,omitempty"`
+}
+
+// TOCEntry is a heading of a rendered project, linked by its anchor ID
+type TOCEntry struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// Activity types understood by the frontend
+const (
+	ActivityTypeCommit = "commit"
+	ActivityTypeTweet  = "tweet"
+	ActivityTypeOther  = "other"
+)
+
+type Activity struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Source    string                 `json:"source"`
+	Content   string                 `json:"content"`
+	Timestamp string                 `json:"timestamp"`
+	URL       string                 `json:"url,omitempty"`
+	IsPrivate bool                   `json:"is_private"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// HeatmapDay is the number of commits made on a given day
+type HeatmapDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// RepoStats aggregates the commits of a repository, private repositories share one anonymous bucket
+type RepoStats struct {
+	RepoName   string `json:"repo_name"`
+	Commits    int    `json:"commits"`
+	IsPrivate  bool   `json:"is_private"`
+	LastCommit string `json:"last_commit"`
+}
+
+type Streaks struct {
+	Current      int    `json:"current"`
+	Longest      int    `json:"longest"`
+	LongestStart string `json:"longest_start,omitempty"`
+	LongestEnd   string `json:"longest_end,omitempty"`
+}
+
+// Distribution counts commits per weekday (Sunday first) and per hour of the day, in UTC
+type Distribution struct {
+	Weekdays [7]int  `json:"weekdays"`
+	Hours    [24]int `json:"hours"`
+}
+
+// LanguageStats sums the changes of enriched commits by repository language
+type LanguageStats struct {
+	Language     string `json:"language"`
+	Commits      int    `json:"commits"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	FilesChanged int    `json:"files_changed"`
+}
+
+type CommitStats struct {
+	TotalCommits    int             `json:"total_commits"`
+	EnrichedCommits int             `json:"enriched_commits"`
+	Heatmap         []HeatmapDay    `json:"heatmap"`
+	Repos           []RepoStats     `json:"repos"`
+	MostActiveRepos []RepoStats     `json:"most_active_repos"`
+	Distribution    Distribution    `json:"distribution"`
+	Streaks         Streaks         `json:"streaks"`
+	Languages       []LanguageStats `json:"languages"`
+	GeneratedAt     string          `json:"generated_at"`
+}
+
+// RateLimitBudget is the GitHub API budget of a rate limit resource such as core or search
+type RateLimitBudget struct {
+	Resource  string `json:"resource"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	Used      int    `json:"used"`
+	Reset     string `json:"reset,omitempty"`
+	// BlockedUntil is set while a secondary rate limit holds requests back
+	BlockedUntil string `json:"blocked_until,omitempty"`
+	Waits        int    `json:"waits"`
+	Retries      int    `json:"retries"`
+}
+
+// AccountRateLimits is the GitHub API budget of the token of a crawled account
+type AccountRateLimits struct {
+	// Account is the name of the account, GITHUB_TOKEN for an unnamed GITHUB_TOKEN account
+	Account string            `json:"account"`
+	Budgets []RateLimitBudget `json:"budgets"`
+}
+
+// CrawlStatus reports the progress of a crawl of every repository. Private repositories
+// are counted but never named.
+type CrawlStatus struct {
+	State          string `json:"state"`
+	StartedAt      string `json:"started_at,omitempty"`
+	FinishedAt     string `json:"finished_at,omitempty"`
+	ReposTotal     int    `json:"repos_total"`
+	ReposDone      int    `json:"repos_done"`
+	ReposFailed    int    `json:"repos_failed"`
+	ReposNew       int    `json:"repos_new"`
+	ReposUnchanged int    `json:"repos_unchanged"`
+	ReposRenamed   int    `json:"repos_renamed"`
+	ReposExcluded  int    `json:"repos_excluded"`
+	ReposDeleted   int    `json:"repos_deleted"`
+	// ReposDeferred are left to the next crawl because the budget of their account ran low
+	ReposDeferred  int      `json:"repos_deferred"`
+	CommitsFetched int      `json:"commits_fetched"`
+	InProgress     []string `json:"in_progress"`
+	FailedRepos    []string `json:"failed_repos"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// RepoSyncState records where the last sync of a repository stopped. Repositories are
+// keyed by their GitHub ID, which survives renames.
+type RepoSyncState struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	Name          string `json:"name"`
+	IsPrivate     bool   `json:"is_private"`
+	DefaultBranch string `json:"default_branch"`
+	LastSeenSHA   string `json:"last_seen_sha"`
+	// Branches maps every synced branch to the newest commit seen on it
+	Branches map[string]string `json:"branches,omitempty"`
+	// BranchPatterns are the configured branch patterns of the sync, the repository is
+	// synced again when they change
+	BranchPatterns []string `json:"branch_patterns,omitempty"`
+	LastSyncedAt   string   `json:"last_synced_at"`
+	// PushedAt is the last push GitHub reported, an unchanged value means nothing to fetch
+	PushedAt string `json:"pushed_at"`
+}
+
+// ProviderSyncState records the last successful sync of a commit provider account and of
+// each of its repositories, commits made before it are not fetched again
+type ProviderSyncState struct {
+	// Key identifies the account, see providerAccount
+	Key      string `json:"key"`
+	SyncedAt string `json:"synced_at"`
+	// Repos holds the last sync of every listed repository by full name, empty for those
+	// never synced. Commits of repositories that are no longer listed are removed.
+	Repos map[string]string `json:"repos,omitempty"`
 }
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// ActivitySource is a feed of activities (commits, posts...) shown on the portfolio
+type ActivitySource interface {
+	// Name identifies the source in the registry and in the API
+	Name() string
+	// FetchAll returns the complete history of the source
+	FetchAll(ctx context.Context) ([]models.Activity, error)
+	// FetchSince returns activities that appeared after the given time
+	FetchSince(ctx context.Context, since time.Time) ([]models.Activity, error)
+}
+
+// changeDetector is implemented by sources that can tell whether anything changed but not
+// what, they are fetched in full again whenever they changed
+type changeDetector interface {
+	ChangedSince(since time.Time) bool
+}
+
+var (
+	activitySources      = make(map[string]ActivitySource)
+	activitySourcesMutex sync.RWMutex
+)
+
+// RegisterActivitySource adds a source to the registry, replacing any source with the same name
+func RegisterActivitySource(source ActivitySource) {
+	activitySourcesMutex.Lock()
+	defer activitySourcesMutex.Unlock()
+
+	activitySources[source.Name()] = source
+}
+
+// GetActivitySources returns the registered sources sorted by name
+func GetActivitySources() []ActivitySource {
+	activitySourcesMutex.RLock()
+	defer activitySourcesMutex.RUnlock()
+
+	sources := make([]ActivitySource, 0, len(activitySources))
+	for _, source := range activitySources {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name() < sources[j].Name()
+	})
+	return sources
+}
+
+// InitActivitySources registers the built-in sources enabled in the configuration
+func InitActivitySources(cfg *config.Config) error {
+	activityRefreshTimeout = cfg.ActivityRefreshTimeout
+	for _, name := range cfg.ActivitySources {
+		switch name {
+		case commitSourceName:
+			RegisterActivitySource(&CommitActivitySource{})
+		case mastodonSourceName:
+			if cfg.MastodonInstance == "" || cfg.MastodonAccount == "" {
+				return errors.New("MASTODON_INSTANCE and MASTODON_ACCOUNT are required for the mastodon activity source")
+			}
+			RegisterActivitySource(NewMastodonSource(cfg.MastodonInstance, cfg.MastodonAccount, cfg.MastodonToken, cfg.HTTPTimeout))
+		default:
+			return fmt.Errorf("unknown activity source: %s", name)
+		}
+	}
+	return nil
+}
+
+type ActivityCache struct {
+	activities  map[string]map[string]cachedActivity
+	lastFetched map[string]time.Time
+	mutex       sync.RWMutex
+}
+
+// cachedActivity keeps the parsed timestamp of an activity, so requests sort without parsing
+type cachedActivity struct {
+	activity models.Activity
+	at       time.Time
+}
+
+var activityCache = NewActivityCache()
+
+// activityRefreshTimeout bounds the refresh of a source, InitActivitySources applies the
+// configured one
+var activityRefreshTimeout = config.Defaults().ActivityRefreshTimeout
+
+func NewActivityCache() *ActivityCache {
+	return &ActivityCache{
+		activities:  make(map[string]map[string]cachedActivity),
+		lastFetched: make(map[string]time.Time),
+	}
+}
+
+// Update merges activities of a source into the cache, keyed by activity ID
+func (c *ActivityCache) Update(source string, activities []models.Activity, fetchedAt time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.activities[source] == nil {
+		c.activities[source] = make(map[string]cachedActivity)
+	}
+	c.add(source, activities, fetchedAt)
+}
+
+// Replace makes activities the complete set of a source, dropping those it no longer
+// returns, such as commits of deleted repositories or of repositories turned private
+func (c *ActivityCache) Replace(source string, activities []models.Activity, fetchedAt time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.activities[source] = make(map[string]cachedActivity, len(activities))
+	c.add(source, activities, fetchedAt)
+}
+
+func (c *ActivityCache) add(source string, activities []models.Activity, fetchedAt time.Time) {
+	for _, activity := range activities {
+		at, _ := time.Parse(time.RFC3339, activity.Timestamp)
+		c.activities[source][activity.ID] = cachedActivity{activity: activity, at: at}
+	}
+	c.lastFetched[source] = fetchedAt
+}
+
+// GetLastFetched returns when the source was last fetched successfully
+func (c *ActivityCache) GetLastFetched(source string) time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.lastFetched[source]
+}
+
+// GetActivities returns the activities of the given sources, newest first
+func (c *ActivityCache) GetActivities(sources []string) []models.Activity {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var cached []cachedActivity
+	for _, source := range sources {
+		for _, entry := range c.activities[source] {
+			cached = append(cached, entry)
+		}
+	}
+
+	sort.Slice(cached, func(i, j int) bool {
+		return cached[i].at.After(cached[j].at)
+	})
+	activities := make([]models.Activity, len(cached))
+	for i, entry := range cached {
+		activities[i] = entry.activity
+	}
+	return activities
+}
+
+// GetActivitiesFromCache returns a page of activities merged across the given sources,
+// or across every registered source when none is given
+func GetActivitiesFromCache(page, limit int, sources []string) ([]models.Activity, int, error) {
+	if len(sources) == 0 {
+		for _, source := range GetActivitySources() {
+			sources = append(sources, source.Name())
+		}
+	}
+
+	activities := activityCache.GetActivities(sources)
+
+	totalCount := len(activities)
+	startIndex := (page - 1) * limit
+	endIndex := startIndex + limit
+
+	if startIndex >= totalCount {
+		return []models.Activity{}, totalCount, nil
+	}
+
+	if endIndex > totalCount {
+		endIndex = totalCount
+	}
+
+	return activities[startIndex:endIndex], totalCount, nil
+}
+
+// UpdateActivityCache refreshes every registered source, fully on first run and incrementally after
+func UpdateActivityCache() error {
+	var errs []error
+	for _, source := range GetActivitySources() {
+		if err := updateActivitySource(source); err != nil {
+			log.Error("Error fetching activities", "source", source.Name(), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func updateActivitySource(source ActivitySource) error {
+	ctx, cancel := context.WithTimeout(context.Background(), activityRefreshTimeout)
+	defer cancel()
+
+	fetchedAt := time.Now().UTC()
+	lastFetched := activityCache.GetLastFetched(source.Name())
+
+	full := lastFetched.IsZero()
+	if detector, ok := source.(changeDetector); ok && !full {
+		if !detector.ChangedSince(lastFetched) {
+			activityCache.Update(source.Name(), nil, fetchedAt)
+			return nil
+		}
+		full = true
+	}
+
+	if full {
+		activities, err := source.FetchAll(ctx)
+		if err != nil {
+			return err
+		}
+		activityCache.Replace(source.Name(), activities, fetchedAt)
+		return nil
+	}
+
+	activities, err := source.FetchSince(ctx, lastFetched)
+	if err != nil {
+		return err
+	}
+	activityCache.Update(source.Name(), activities, fetchedAt)
+	return nil
+}
+
+func StartActivityUpdateScheduler(interval time.Duration) {
+	if err := UpdateActivityCache(); err != nil {
+		log.Error("Error initializing activity cache", "error", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := UpdateActivityCache(); err != nil {
+				log.Error("Error updating activity cache", "error", err)
+			}
+		}
+	}()
+}
+
+const commitSourceName = "commits"
+
+// CommitActivitySource exposes the commit cache as an activity source.
+// The commit cache keeps itself up to date, so this source only reads from it.
+type CommitActivitySource struct{}
+
+func (s *CommitActivitySource) Name() string {
+	return commitSourceName
+}
+
+func (s *CommitActivitySource) FetchAll(ctx context.Context) ([]models.Activity, error) {
+	// Obfuscation is deterministic, so private commits keep the same ID across fetches
+	commits := ObfuscatePrivateCommits(cache.GetAllCommits())
+
+	activities := make([]models.Activity, 0, len(commits))
+	for _, commit := range commits {
+		activities = append(activities, commitToActivity(commit))
+	}
+	return activities, nil
+}
+
+// ChangedSince reports whether the commit cache was updated after since. Commits may be
+// authored long before they reach the cache, or be removed from it, so any update is treated
+// as a change of the whole set.
+func (s *CommitActivitySource) ChangedSince(since time.Time) bool {
+	return cache.GetLastUpdated().After(since)
+}
+
+func (s *CommitActivitySource) FetchSince(ctx context.Context, since time.Time) ([]models.Activity, error) {
+	if !s.ChangedSince(since) {
+		return nil, nil
+	}
+	return s.FetchAll(ctx)
+}
+
+func commitToActivity(commit models.Commit) models.Activity {
+	return models.Activity{
+		ID:        commit.ID,
+		Type:      models.ActivityTypeCommit,
+		Source:    commitSourceName,
+		Content:   commit.Message,
+		Timestamp: commit.Timestamp,
+		URL:       commit.URL,
+		IsPrivate: commit.IsPrivate,
+		Metadata: map[string]interface{}{
+			"repo_name": commit.RepoName,
+		},
+	}
+}
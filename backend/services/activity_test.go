@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+type fakeActivitySource struct {
+	name       string
+	all        []models.Activity
+	recent     []models.Activity
+	sinceCalls int
+}
+
+func (s *fakeActivitySource) Name() string {
+	return s.name
+}
+
+func (s *fakeActivitySource) FetchAll(ctx context.Context) ([]models.Activity, error) {
+	return s.all, nil
+}
+
+func (s *fakeActivitySource) FetchSince(ctx context.Context, since time.Time) ([]models.Activity, error) {
+	s.sinceCalls++
+	return s.recent, nil
+}
+
+func TestUpdateActivityCacheMergesSources(t *testing.T) {
+	now := time.Now().UTC()
+	at := func(d time.Duration) string { return now.Add(-d).Format(time.RFC3339) }
+
+	posts := &fakeActivitySource{
+		name: "posts",
+		all: []models.Activity{
+			{ID: "post-1", Type: models.ActivityTypeTweet, Source: "posts", Timestamp: at(3 * time.Hour)},
+		},
+		recent: []models.Activity{
+			{ID: "post-2", Type: models.ActivityTypeTweet, Source: "posts", Timestamp: at(time.Minute)},
+		},
+	}
+	notes := &fakeActivitySource{
+		name: "notes",
+		all: []models.Activity{
+			{ID: "note-1", Type: models.ActivityTypeOther, Source: "notes", Timestamp: at(2 * time.Hour)},
+		},
+	}
+
+	originalSources := activitySources
+	originalCache := activityCache
+	activitySources = make(map[string]ActivitySource)
+	activityCache = NewActivityCache()
+	defer func() {
+		activitySources = originalSources
+		activityCache = originalCache
+	}()
+
+	RegisterActivitySource(posts)
+	RegisterActivitySource(notes)
+
+	// First run fetches everything, second run only what is new
+	for i := 0; i < 2; i++ {
+		if err := UpdateActivityCache(); err != nil {
+			t.Fatalf("UpdateActivityCache returned an error: %v", err)
+		}
+	}
+	if posts.sinceCalls != 1 {
+		t.Errorf("Expected 1 incremental fetch, got %d", posts.sinceCalls)
+	}
+
+	activities, totalCount, err := GetActivitiesFromCache(1, 10, nil)
+	if err != nil {
+		t.Fatalf("GetActivitiesFromCache returned an error: %v", err)
+	}
+	if totalCount != 3 {
+		t.Fatalf("Expected 3 activities, got %d", totalCount)
+	}
+
+	expectedOrder := []string{"post-2", "note-1", "post-1"}
+	for i, id := range expectedOrder {
+		if activities[i].ID != id {
+			t.Errorf("Expected activity %d to be %s, got %s", i, id, activities[i].ID)
+		}
+	}
+
+	activities, totalCount, _ = GetActivitiesFromCache(1, 10, []string{"notes"})
+	if totalCount != 1 || activities[0].ID != "note-1" {
+		t.Errorf("Expected only note-1 when filtering by source, got %+v", activities)
+	}
+}
+
+func TestUpdateActivityCacheReplacesCommitSnapshot(t *testing.T) {
+	originalSources, originalActivities, originalCache := activitySources, activityCache, cache
+	activitySources = make(map[string]ActivitySource)
+	activityCache = NewActivityCache()
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() {
+		activitySources, activityCache, cache = originalSources, originalActivities, originalCache
+	}()
+	RegisterActivitySource(&CommitActivitySource{})
+
+	cache.Update([]models.Commit{
+		{ID: "a", RepoName: "gordon", Message: "feat: public", Timestamp: "2024-08-01T10:00:00Z"},
+		{ID: "b", RepoName: "gart", Message: "feat: gone", Timestamp: "2024-08-01T11:00:00Z"},
+	})
+	if err := UpdateActivityCache(); err != nil {
+		t.Fatalf("UpdateActivityCache returned an error: %v", err)
+	}
+
+	// The repository of b is deleted, the next crawl drops its commits
+	cache.Remove([]string{"b"})
+	cache.MarkUpdated(time.Now().UTC().Add(time.Second))
+	if err := UpdateActivityCache(); err != nil {
+		t.Fatalf("UpdateActivityCache returned an error: %v", err)
+	}
+
+	activities, totalCount, _ := GetActivitiesFromCache(1, 10, nil)
+	if totalCount != 1 || activities[0].ID != "a" {
+		t.Errorf("Expected the activities of removed commits to be dropped, got %+v", activities)
+	}
+}
@@ -1,9 +1,13 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"portfolio-backend/config"
 	"portfolio-backend/models"
-	"sort"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +19,13 @@ type CommitCache struct {
 	commits     map[string]models.Commit
 	lastUpdated atomic.Value
 	mutex       sync.RWMutex
+	store       CommitStore
+	index       *commitIndex
+	revision    atomic.Uint64
+	// repos holds the sync state of every repository, by GitHub ID
+	repos map[int64]models.RepoSyncState
+	// providers holds the sync state of every commit provider account, by key
+	providers map[string]models.ProviderSyncState
 }
 
 var cache *CommitCache
@@ -22,18 +33,278 @@ var cache *CommitCache
 func init() {
 	cache = &CommitCache{
 		commits: make(map[string]models.Commit),
+		index:   newCommitIndex(),
 	}
 	cache.lastUpdated.Store(time.Now().UTC())
 }
 
+// InitCommitCache opens the configured store and restores previously cached commits
+func InitCommitCache(cfg *config.Config) error {
+	store, err := NewCommitStore(cfg.CacheBackend, cfg.CachePath)
+	if err != nil {
+		return err
+	}
+
+	commits, lastUpdated, err := store.Load()
+	if err != nil {
+		store.Close()
+		return err
+	}
+	repos, err := store.LoadRepos()
+	if err != nil {
+		store.Close()
+		return err
+	}
+	providers, err := store.LoadProviders()
+	if err != nil {
+		store.Close()
+		return err
+	}
+
+	newCache := &CommitCache{
+		commits:   make(map[string]models.Commit, len(commits)),
+		store:     store,
+		repos:     make(map[int64]models.RepoSyncState, len(repos)),
+		providers: make(map[string]models.ProviderSyncState, len(providers)),
+	}
+	for _, commit := range commits {
+		newCache.commits[commit.ID] = commit
+	}
+	for _, repo := range repos {
+		newCache.repos[repo.ID] = repo
+	}
+	for _, provider := range providers {
+		newCache.providers[provider.Key] = provider
+	}
+	newCache.index = buildCommitIndex(newCache.commits)
+	if lastUpdated.IsZero() {
+		lastUpdated = time.Now().UTC()
+	}
+	newCache.lastUpdated.Store(lastUpdated)
+
+	cache = newCache
+	log.Info("Commit cache initialized", "backend", cfg.CacheBackend, "commits", len(commits), "repos", len(repos), "last_updated", lastUpdated)
+	return nil
+}
+
+// CloseCommitCache releases the store behind the commit cache
+func CloseCommitCache() error {
+	if cache.store == nil {
+		return nil
+	}
+	return cache.store.Close()
+}
+
 func (c *CommitCache) Update(newCommits []models.Commit) {
+	c.apply(newCommits, time.Now().UTC())
+}
+
+// Merge adds commits without moving the last update time, for partial results such as
+// the repositories of a running crawl, enriched commits or webhook pushes. Only a complete
+// sync, through Update or MarkUpdated, tells readers such as the activity feed that the
+// whole set changed.
+func (c *CommitCache) Merge(commits []models.Commit) {
+	c.apply(commits, time.Time{})
+}
+
+// MarkUpdated records that every commit made before the given time is cached
+func (c *CommitCache) MarkUpdated(lastUpdated time.Time) {
+	c.apply(nil, lastUpdated)
+}
+
+// apply adds commits and sets the last update time, a zero lastUpdated keeps the current
+// one. It is read under the lock, so a concurrent MarkUpdated is not written over.
+func (c *CommitCache) apply(commits []models.Commit, lastUpdated time.Time) {
+	// Commits are merged with their cached version and sorted by the index, the caller
+	// keeps its slice as it was
+	newCommits := slices.Clone(commits)
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	for _, commit := range newCommits {
+	if lastUpdated.IsZero() {
+		lastUpdated = c.GetLastUpdated()
+	}
+	index := c.getIndex()
+	for i, commit := range newCommits {
+		if previous, ok := c.commits[commit.ID]; ok {
+			commit = mergeCommit(previous, commit)
+			newCommits[i] = commit
+		}
 		c.commits[commit.ID] = commit
 	}
-	c.lastUpdated.Store(time.Now().UTC())
+	index.addAll(newCommits)
+	c.lastUpdated.Store(lastUpdated)
+	c.revision.Add(1)
+
+	if c.store != nil {
+		if err := c.store.Save(newCommits, lastUpdated); err != nil {
+			log.Error("Error persisting commit cache", "error", err)
+		}
+	}
+}
+
+// mergeCommit combines a commit fetched again with its cached version. Fetching a commit
+// again must not drop what enrichment added to it, nor the other branches it was seen on.
+func mergeCommit(previous, commit models.Commit) models.Commit {
+	// A mirror on disk knows less about a commit than the forge it was pushed to
+	if commit.Forge == ForgeLocal && previous.Forge != ForgeLocal {
+		commit.RepoName = previous.RepoName
+		commit.URL = previous.URL
+		commit.IsPrivate = previous.IsPrivate
+		commit.Forge = previous.Forge
+	}
+	if commit.Account == "" {
+		commit.Account = previous.Account
+	}
+	if commit.Changes == nil {
+		commit.Changes = previous.Changes
+		if commit.Language == "" {
+			commit.Language = previous.Language
+		}
+	}
+
+	branches := append([]string{}, previous.Branches...)
+	for _, branch := range commit.Branches {
+		if !slices.Contains(branches, branch) {
+			branches = append(branches, branch)
+		}
+	}
+	if len(branches) > 0 {
+		commit.Branches = branches
+	}
+	return commit
+}
+
+// has reports whether a commit is cached
+func (c *CommitCache) has(id string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, ok := c.commits[id]
+	return ok
+}
+
+// Remove drops the commits with the given IDs
+func (c *CommitCache) Remove(ids []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	index := c.getIndex()
+	for _, id := range ids {
+		if entry, ok := index.entries[id]; ok {
+			index.remove(entry)
+		}
+		delete(c.commits, id)
+	}
+	c.revision.Add(1)
+
+	if c.store != nil {
+		if err := c.store.Delete(ids); err != nil {
+			log.Error("Error deleting commits from store", "error", err)
+		}
+	}
+}
+
+// commitsOfRepo returns the cached commits of a GitHub repository, found by their URL
+func (c *CommitCache) commitsOfRepo(owner, name string) []models.Commit {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	prefix := fmt.Sprintf("https://github.com/%s/%s/commit/", owner, name)
+	var commits []models.Commit
+	for _, commit := range c.commits {
+		if strings.HasPrefix(strings.ToLower(commit.URL), strings.ToLower(prefix)) {
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
+// commitsOfForgeRepo returns the cached commits of a repository of a forge other than the
+// one the crawler syncs, by full name
+func (c *CommitCache) commitsOfForgeRepo(forge, fullName string) []models.Commit {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var commits []models.Commit
+	for _, commit := range c.commits {
+		if commit.Forge == forge && strings.EqualFold(commitRepoFullName(commit), fullName) {
+			commits = append(commits, commit)
+		}
+	}
+	return commits
+}
+
+// RepoStates returns the sync state of every known repository, by GitHub ID
+func (c *CommitCache) RepoStates() map[int64]models.RepoSyncState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	repos := make(map[int64]models.RepoSyncState, len(c.repos))
+	for id, repo := range c.repos {
+		repos[id] = repo
+	}
+	return repos
+}
+
+// SaveRepoStates replaces the sync state of every repository
+func (c *CommitCache) SaveRepoStates(repos []models.RepoSyncState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.repos = make(map[int64]models.RepoSyncState, len(repos))
+	for _, repo := range repos {
+		c.repos[repo.ID] = repo
+	}
+
+	if c.store != nil {
+		if err := c.store.SaveRepos(repos); err != nil {
+			log.Error("Error persisting repository sync states", "error", err)
+		}
+	}
+}
+
+// ProviderState returns the last successful sync of a commit provider account, with only its
+// key set when it never was synced
+func (c *CommitCache) ProviderState(key string) models.ProviderSyncState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	provider, ok := c.providers[key]
+	if !ok {
+		return models.ProviderSyncState{Key: key}
+	}
+	return provider
+}
+
+// SaveProviderState records the last successful sync of a commit provider account
+func (c *CommitCache) SaveProviderState(provider models.ProviderSyncState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.providers == nil {
+		c.providers = make(map[string]models.ProviderSyncState)
+	}
+	c.providers[provider.Key] = provider
+
+	if c.store != nil {
+		if err := c.store.SaveProvider(provider); err != nil {
+			log.Error("Error persisting commit provider sync state", "error", err)
+		}
+	}
+}
+
+// GetRevision returns a number that changes every time cached commits change
+func (c *CommitCache) GetRevision() uint64 {
+	return c.revision.Load()
+}
+
+// getIndex returns the commit index, building it on first use. The caller must hold the write lock.
+func (c *CommitCache) getIndex() *commitIndex {
+	if c.index == nil {
+		c.index = buildCommitIndex(c.commits)
+	}
+	return c.index
 }
 
 func (c *CommitCache) GetLastUpdated() time.Time {
@@ -53,27 +324,15 @@ func (c *CommitCache) GetAllCommits() []models.Commit {
 	}
 	return commits
 }
-func GetAllCommitsFromCache(page, limit int) ([]models.Commit, int, error) {
+
+// GetAllCommitsFromCache returns a page of commits matching the filter, newest first
+func GetAllCommitsFromCache(page, limit int, filter CommitFilter) ([]models.Commit, int, error) {
 	cache.mutex.RLock()
 	defer cache.mutex.RUnlock()
 
-	// Convert map to slice for pagination
-	commits := make([]models.Commit, 0, len(cache.commits))
-	for _, commit := range cache.commits {
-		commits = append(commits, commit)
-	}
+	entries := cache.readIndex().matching(filter)
 
-	// Sort commits by timestamp (newest first)
-	sort.Slice(commits, func(i, j int) bool {
-		timeI, _ := time.Parse(time.RFC3339, commits[i].Timestamp)
-		timeJ, _ := time.Parse(time.RFC3339, commits[j].Timestamp)
-		return timeI.After(timeJ)
-	})
-
-	// Apply obfuscation to private commits
-	obfuscatedCommits := ObfuscatePrivateCommits(commits)
-
-	totalCount := len(obfuscatedCommits)
+	totalCount := len(entries)
 	startIndex := (page - 1) * limit
 	endIndex := startIndex + limit
 
@@ -85,60 +344,90 @@ func GetAllCommitsFromCache(page, limit int) ([]models.Commit, int, error) {
 		endIndex = totalCount
 	}
 
-	return obfuscatedCommits[startIndex:endIndex], totalCount, nil
+	return publicCommits(entries[startIndex:endIndex]), totalCount, nil
 }
 
-func UpdateCommitCache() error {
-	log.Info("Updating commit cache...")
+// readIndex returns the commit index for readers holding the read lock
+func (c *CommitCache) readIndex() *commitIndex {
+	if c.index == nil {
+		return buildCommitIndex(c.commits)
+	}
+	return c.index
+}
 
-	var lastUpdate time.Time
-	if lastUpdateValue := cache.lastUpdated.Load(); lastUpdateValue != nil {
-		lastUpdate = lastUpdateValue.(time.Time)
+// publicCommits copies the public view of the entries, private commits are already obfuscated
+func publicCommits(entries []*commitEntry) []models.Commit {
+	commits := make([]models.Commit, len(entries))
+	for i, entry := range entries {
+		commits[i] = entry.public
 	}
+	return commits
+}
 
-	var recentCommits []models.Commit
-	var err error
+// UpdateCommitCache syncs every repository that changed since its last sync. Commits
+// are published per repository, and the last update time only moves once the sync
+// succeeded, to when it started.
+func UpdateCommitCache() error {
+	log.Info("Updating commit cache...")
 
-	if len(cache.commits) == 0 {
-		// Cache is empty, fetch all commits
-		recentCommits, err = FetchAllCommitsFromAllRepos()
-	} else {
-		// Cache has data, fetch only recent commits
-		recentCommits, err = FetchRecentCommits(lastUpdate)
+	startedAt := time.Now().UTC()
+	var errs []error
+	if GetGitHubClient() != nil {
+		if err := FetchAllCommitsFromAllRepos(context.Background(), cache.Merge); err != nil {
+			log.Error("Error fetching commits", "error", err)
+			errs = append(errs, err)
+		} else {
+			status := crawler.Status()
+			log.Info("GitHub repositories synced", "repos", status.ReposTotal, "unchanged", status.ReposUnchanged, "failed", status.ReposFailed, "deferred", status.ReposDeferred, "new_commits", status.CommitsFetched)
+		}
 	}
-
-	if err != nil {
-		log.Error("Error fetching commits", "error", err)
-		return err
+	// Other forges are merged into the same cache, commits are tagged with their forge
+	if err := SyncCommitProviders(context.Background(), cache.Merge); err != nil {
+		log.Error("Error fetching commits from forges", "error", err)
+		errs = append(errs, err)
 	}
-
-	if len(recentCommits) == 0 {
-		log.Info("No new commits to add to cache")
-		return nil
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
+	cache.MarkUpdated(startedAt)
 
-	cache.Update(recentCommits)
-	log.Info("Cache update completed", "new_commits", len(recentCommits))
+	log.Info("Cache update completed")
 	return nil
 }
 
-func StartCacheUpdateScheduler() {
+func StartCacheUpdateScheduler(interval time.Duration) {
 	// Debug
 	fmt.Println("Starting cache update scheduler...")
+	if GetGitHubClient() == nil && !hasCommitProviders() {
+		log.Warn("Neither GitHub nor any other forge is configured, commit cache will stay empty")
+		return
+	}
+
 	// Initial load of all commits
 	if err := UpdateCommitCache(); err != nil {
 		log.Error("Error initializing commit cache", "error", err)
 	}
+	enrichCommitCache()
 
 	// Schedule periodic updates
 	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		for range ticker.C {
 			if err := UpdateCommitCache(); err != nil {
 				log.Error("Error updating commit cache", "error", err)
 			}
+			enrichCommitCache()
 		}
 	}()
 }
+
+// enrichCommitCache runs one enrichment cycle, the remaining commits are picked up by the next one
+func enrichCommitCache() {
+	ctx, cancel := context.WithTimeout(context.Background(), enrichmentTimeout)
+	defer cancel()
+	if err := EnrichCommitCache(ctx); err != nil {
+		log.Warn("Commit enrichment paused", "error", err)
+	}
+}
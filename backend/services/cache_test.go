@@ -2,8 +2,8 @@ package services
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"strings"
 	"testing"
 	"time"
 
@@ -15,8 +15,9 @@ import (
 
 func TestUpdateCommitCache(t *testing.T) {
 	// Set up mock data
-	now := time.Now()
-	mockCommits := []*github.CommitResult{
+	now := time.Now().UTC().Truncate(time.Second)
+	lastSynced := now.Add(-30 * time.Minute)
+	mockCommits := []*github.RepositoryCommit{
 		{
 			SHA: github.String("new-commit-id"),
 			Commit: &github.Commit{
@@ -26,34 +27,43 @@ func TestUpdateCommitCache(t *testing.T) {
 				},
 			},
 			HTMLURL: github.String("https://github.com/test/test-repo/commit/new-commit-id"),
-			Repository: &github.Repository{
-				Name:    github.String("test-repo"),
-				Private: github.Bool(false),
+		},
+		{
+			SHA: github.String("old-commit-id"),
+			Commit: &github.Commit{
+				Message: github.String("Old commit"),
+				Author: &github.CommitAuthor{
+					Date: &github.Timestamp{Time: now.Add(-1 * time.Hour)},
+				},
 			},
+			HTMLURL: github.String("https://github.com/test/test-repo/commit/old-commit-id"),
 		},
 	}
 
 	// Create mocked HTTP client
 	mockedHTTPClient := mock.NewMockedHTTPClient(
 		mock.WithRequestMatch(
-			mock.GetUsersByUsername,
-			github.User{Login: github.String("testuser")},
-		),
-		mock.WithRequestMatch(
-			mock.GetUser,
-			github.User{Login: github.String("testuser")},
+			mock.GetUserRepos,
+			[]*github.Repository{
+				{
+					ID:            github.Int64(1),
+					Name:          github.String("test-repo"),
+					Owner:         &github.User{Login: github.String("test")},
+					DefaultBranch: github.String("main"),
+					PushedAt:      &github.Timestamp{Time: now},
+				},
+			},
 		),
 		mock.WithRequestMatchHandler(
-			mock.GetSearchCommits,
+			mock.GetReposCommitsByOwnerByRepo,
 			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				query := r.URL.Query().Get("q")
-				if !strings.Contains(query, "author:testuser") {
+				// Only commits made since the last sync are listed
+				since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+				if err != nil || !since.Before(lastSynced) || r.URL.Query().Get("sha") != "main" {
 					http.Error(w, "Unexpected query", http.StatusBadRequest)
 					return
 				}
-				json.NewEncoder(w).Encode(github.CommitsSearchResult{
-					Commits: mockCommits,
-				})
+				json.NewEncoder(w).Encode(mockCommits)
 			}),
 		),
 	)
@@ -63,7 +73,9 @@ func TestUpdateCommitCache(t *testing.T) {
 	githubClient = github.NewClient(mockedHTTPClient)
 	defer func() { githubClient = originalClient }()
 
-	// Initialize cache with some old commits
+	// Initialize cache with an old commit and the sync state of its repository
+	originalCache := cache
+	defer func() { cache = originalCache }()
 	cache = &CommitCache{
 		commits: map[string]models.Commit{
 			"old-commit-id": {
@@ -75,10 +87,21 @@ func TestUpdateCommitCache(t *testing.T) {
 				IsPrivate: false,
 			},
 		},
+		repos: map[int64]models.RepoSyncState{
+			1: {
+				ID:            1,
+				Owner:         "test",
+				Name:          "test-repo",
+				DefaultBranch: "main",
+				LastSeenSHA:   "old-commit-id",
+				LastSyncedAt:  lastSynced.Format(time.RFC3339),
+				PushedAt:      lastSynced.Format(time.RFC3339),
+			},
+		},
 	}
 
 	// Set the lastUpdated time using the atomic.Value Store method
-	cache.lastUpdated.Store(now.Add(-30 * time.Minute))
+	cache.lastUpdated.Store(lastSynced)
 
 	// Run the update
 	err := UpdateCommitCache()
@@ -97,4 +120,108 @@ func TestUpdateCommitCache(t *testing.T) {
 	} else if newestCommit.ID != "new-commit-id" {
 		t.Errorf("Expected newest commit to be 'new-commit-id', got %s", newestCommit.ID)
 	}
+
+	// Paging stops at the last seen commit
+	if status := GetCrawlStatus(); status.CommitsFetched != 1 {
+		t.Errorf("Expected only the new commit to be fetched, got %d", status.CommitsFetched)
+	}
+	state := cache.repos[1]
+	if state.LastSeenSHA != "new-commit-id" || state.PushedAt != now.Format(time.RFC3339) {
+		t.Errorf("Expected the sync state to move forward, got %+v", state)
+	}
+}
+
+func TestMergeKeepsLastUpdatedAndCallerSlice(t *testing.T) {
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+	lastUpdated := time.Date(2024, 8, 1, 11, 0, 0, 0, time.UTC)
+	cache.MarkUpdated(lastUpdated)
+	cache.Merge([]models.Commit{{ID: "a", RepoName: "gordon", Timestamp: "2024-08-01T10:00:00Z", Branches: []string{"main"}}})
+
+	commits := []models.Commit{
+		{ID: "a", RepoName: "gordon", Timestamp: "2024-08-01T10:00:00Z", Branches: []string{"feat/x"}},
+		{ID: "b", RepoName: "gordon", Timestamp: "2024-08-01T12:00:00Z"},
+	}
+	cache.Merge(commits)
+
+	if commits[0].ID != "a" || len(commits[0].Branches) != 1 {
+		t.Errorf("Expected the merged slice to be left untouched, got %+v", commits)
+	}
+	if branches := cache.commits["a"].Branches; len(branches) != 2 {
+		t.Errorf("Expected the cached commit to gather both branches, got %v", branches)
+	}
+	if !cache.GetLastUpdated().Equal(lastUpdated) {
+		t.Errorf("Expected the last update time to be kept, got %s", cache.GetLastUpdated())
+	}
+}
+
+// benchmarkCache fills the cache with n commits, one in ten being private
+func benchmarkCache(b *testing.B, n int) {
+	b.Helper()
+	now := time.Now().UTC()
+	commits := make([]models.Commit, n)
+	for i := range commits {
+		commits[i] = models.Commit{
+			ID:        fmt.Sprintf("%040d", i),
+			RepoName:  fmt.Sprintf("repo-%d", i%50),
+			Message:   fmt.Sprintf("feat: change number %d", i),
+			Timestamp: now.Add(-time.Duration(i) * time.Minute).Format(time.RFC3339),
+			IsPrivate: i%10 == 0,
+		}
+	}
+
+	originalCache := cache
+	b.Cleanup(func() { cache = originalCache })
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	cache.Update(commits)
+}
+
+// The cost of a page must not grow with the number of cached commits
+func BenchmarkGetAllCommitsFromCache(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		for _, limit := range []int{20, 100} {
+			b.Run(fmt.Sprintf("commits=%d/limit=%d", n, limit), func(b *testing.B) {
+				benchmarkCache(b, n)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, _, err := GetAllCommitsFromCache(3, limit, DefaultCommitFilter()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGetCommitsPageFromCache(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("commits=%d", n), func(b *testing.B) {
+			benchmarkCache(b, n)
+			first, err := GetCommitsPageFromCache("", 20, DefaultCommitFilter())
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := GetCommitsPageFromCache(first.NextCursor, 20, DefaultCommitFilter()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCommitCacheUpdate(b *testing.B) {
+	benchmarkCache(b, 10000)
+	now := time.Now().UTC()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Update([]models.Commit{{
+			ID:        fmt.Sprintf("new-%d", i),
+			RepoName:  "gordon",
+			Message:   "fix: incremental insert",
+			Timestamp: now.Add(time.Duration(i) * time.Second).Format(time.RFC3339),
+		}})
+	}
 }
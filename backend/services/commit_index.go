@@ -0,0 +1,379 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"portfolio-backend/models"
+)
+
+// CommitFilter narrows down the commits returned from the cache
+type CommitFilter struct {
+	// Repo only matches public repositories, private names are never searchable
+	Repo  string
+	Since time.Time
+	Until time.Time
+	// Query is matched against the words of public commit messages, every word must match
+	Query string
+	// Types keeps commits of any of the given Conventional Commits types
+	Types []string
+	// Branch only matches public commits, like Repo
+	Branch         string
+	IncludePrivate bool
+	ExcludeMerges  bool
+}
+
+// DefaultCommitFilter matches every commit
+func DefaultCommitFilter() CommitFilter {
+	return CommitFilter{IncludePrivate: true}
+}
+
+type idSet map[string]struct{}
+
+func (s idSet) add(id string) {
+	s[id] = struct{}{}
+}
+
+// commitEntry is a cached commit with its parsed timestamp and its public (obfuscated) view
+type commitEntry struct {
+	commit models.Commit
+	public models.Commit
+	time   time.Time
+}
+
+func newCommitEntry(commit models.Commit) *commitEntry {
+	public := publicCommit(commit)
+	// Parsed from the public view, so redacted messages do not leak their type or scope
+	applyConventionalCommit(&public)
+	timestamp, _ := time.Parse(time.RFC3339, commit.Timestamp)
+	return &commitEntry{commit: commit, public: public, time: timestamp}
+}
+
+// before reports whether e comes before other in feed order: newest first, then by descending public ID
+func (e *commitEntry) before(other *commitEntry) bool {
+	if !e.time.Equal(other.time) {
+		return e.time.After(other.time)
+	}
+	return e.public.ID > other.public.ID
+}
+
+// commitIndex keeps commits in feed order and maps repos, message words and flags to
+// commit IDs, so requests do not sort or scan every commit
+type commitIndex struct {
+	ordered  []*commitEntry
+	entries  map[string]*commitEntry
+	byRepo   map[string]idSet
+	byTerm   map[string]idSet
+	byType   map[string]idSet
+	byBranch map[string]idSet
+	private  idSet
+	merges   idSet
+}
+
+func newCommitIndex() *commitIndex {
+	return &commitIndex{
+		entries:  make(map[string]*commitEntry),
+		byRepo:   make(map[string]idSet),
+		byTerm:   make(map[string]idSet),
+		byType:   make(map[string]idSet),
+		byBranch: make(map[string]idSet),
+		private:  make(idSet),
+		merges:   make(idSet),
+	}
+}
+
+func buildCommitIndex(commits map[string]models.Commit) *commitIndex {
+	idx := newCommitIndex()
+	batch := make([]models.Commit, 0, len(commits))
+	for _, commit := range commits {
+		batch = append(batch, commit)
+	}
+	idx.addAll(batch)
+	return idx
+}
+
+// addAll indexes a batch of commits, replacing commits already indexed under the same ID.
+// Small batches are inserted in place, large ones are appended and sorted once.
+func (idx *commitIndex) addAll(commits []models.Commit) {
+	bulk := len(commits) > len(idx.ordered)/8
+	removed := make(map[*commitEntry]bool)
+	for _, commit := range commits {
+		if previous, ok := idx.entries[commit.ID]; ok {
+			if bulk {
+				// The order is restored after the batch, drop replaced entries then
+				removed[previous] = true
+				idx.removeTerms(previous)
+			} else {
+				idx.remove(previous)
+			}
+		}
+
+		entry := newCommitEntry(commit)
+		idx.entries[commit.ID] = entry
+		idx.addTerms(entry)
+
+		if bulk {
+			idx.ordered = append(idx.ordered, entry)
+			continue
+		}
+		i := idx.search(entry)
+		idx.ordered = append(idx.ordered, nil)
+		copy(idx.ordered[i+1:], idx.ordered[i:])
+		idx.ordered[i] = entry
+	}
+
+	if bulk {
+		if len(removed) > 0 {
+			kept := idx.ordered[:0]
+			for _, entry := range idx.ordered {
+				if !removed[entry] {
+					kept = append(kept, entry)
+				}
+			}
+			idx.ordered = kept
+		}
+		sort.Slice(idx.ordered, func(i, j int) bool {
+			return idx.ordered[i].before(idx.ordered[j])
+		})
+	}
+}
+
+// search returns the position of the first entry not before the given one
+func (idx *commitIndex) search(entry *commitEntry) int {
+	return sort.Search(len(idx.ordered), func(i int) bool {
+		return !idx.ordered[i].before(entry)
+	})
+}
+
+func (idx *commitIndex) remove(entry *commitEntry) {
+	if i := idx.search(entry); i < len(idx.ordered) && idx.ordered[i] == entry {
+		idx.ordered = append(idx.ordered[:i], idx.ordered[i+1:]...)
+	}
+	delete(idx.entries, entry.commit.ID)
+	idx.removeTerms(entry)
+}
+
+func (idx *commitIndex) addTerms(entry *commitEntry) {
+	commit := entry.commit
+	if isMergeCommit(commit.Message) {
+		idx.merges.add(commit.ID)
+	}
+	if commitType := entry.public.Type; commitType != "" {
+		if idx.byType[commitType] == nil {
+			idx.byType[commitType] = make(idSet)
+		}
+		idx.byType[commitType].add(commit.ID)
+	}
+
+	// Private repo names and messages stay out of the index so filters cannot leak them
+	if entry.public.IsPrivate {
+		idx.private.add(commit.ID)
+		return
+	}
+
+	repo := strings.ToLower(commit.RepoName)
+	if idx.byRepo[repo] == nil {
+		idx.byRepo[repo] = make(idSet)
+	}
+	idx.byRepo[repo].add(commit.ID)
+
+	for _, term := range tokenize(commit.Message) {
+		if idx.byTerm[term] == nil {
+			idx.byTerm[term] = make(idSet)
+		}
+		idx.byTerm[term].add(commit.ID)
+	}
+
+	for _, branch := range commit.Branches {
+		if idx.byBranch[branch] == nil {
+			idx.byBranch[branch] = make(idSet)
+		}
+		idx.byBranch[branch].add(commit.ID)
+	}
+}
+
+func (idx *commitIndex) removeTerms(entry *commitEntry) {
+	commit := entry.commit
+	delete(idx.merges, commit.ID)
+	delete(idx.private, commit.ID)
+
+	if ids := idx.byType[entry.public.Type]; ids != nil {
+		delete(ids, commit.ID)
+		if len(ids) == 0 {
+			delete(idx.byType, entry.public.Type)
+		}
+	}
+
+	repo := strings.ToLower(commit.RepoName)
+	if ids := idx.byRepo[repo]; ids != nil {
+		delete(ids, commit.ID)
+		if len(ids) == 0 {
+			delete(idx.byRepo, repo)
+		}
+	}
+
+	for _, term := range tokenize(commit.Message) {
+		if ids := idx.byTerm[term]; ids != nil {
+			delete(ids, commit.ID)
+			if len(ids) == 0 {
+				delete(idx.byTerm, term)
+			}
+		}
+	}
+
+	for _, branch := range commit.Branches {
+		if ids := idx.byBranch[branch]; ids != nil {
+			delete(ids, commit.ID)
+			if len(ids) == 0 {
+				delete(idx.byBranch, branch)
+			}
+		}
+	}
+}
+
+// matching returns the entries matching the filter in feed order. Without indexed criteria
+// or flags, it is a window of the ordered entries found by binary search, so its cost does
+// not depend on the number of commits. The caller must hold the cache lock while using it.
+func (idx *commitIndex) matching(filter CommitFilter) []*commitEntry {
+	lo, hi := 0, len(idx.ordered)
+	if !filter.Until.IsZero() {
+		lo = sort.Search(len(idx.ordered), func(i int) bool {
+			return !idx.ordered[i].time.After(filter.Until)
+		})
+	}
+	if !filter.Since.IsZero() {
+		hi = sort.Search(len(idx.ordered), func(i int) bool {
+			return idx.ordered[i].time.Before(filter.Since)
+		})
+	}
+	if hi < lo {
+		hi = lo
+	}
+	window := idx.ordered[lo:hi]
+
+	candidates := idx.candidates(filter)
+	flagged := (!filter.IncludePrivate && len(idx.private) > 0) || (filter.ExcludeMerges && len(idx.merges) > 0)
+	if candidates == nil && !flagged {
+		return window
+	}
+
+	var result []*commitEntry
+	if candidates != nil && len(candidates) < len(window) {
+		// Few candidates, sorting them is cheaper than walking the window
+		for id := range candidates {
+			entry := idx.entries[id]
+			if idx.excluded(id, filter) ||
+				(!filter.Since.IsZero() && entry.time.Before(filter.Since)) ||
+				(!filter.Until.IsZero() && entry.time.After(filter.Until)) {
+				continue
+			}
+			result = append(result, entry)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].before(result[j])
+		})
+		return result
+	}
+
+	for _, entry := range window {
+		if candidates != nil {
+			if _, ok := candidates[entry.commit.ID]; !ok {
+				continue
+			}
+		}
+		if !idx.excluded(entry.commit.ID, filter) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// candidates returns the IDs matching the indexed parts of the filter, or nil when
+// the filter has no indexed criteria and every commit is a candidate
+func (idx *commitIndex) candidates(filter CommitFilter) idSet {
+	var sets []idSet
+	if filter.Repo != "" {
+		sets = append(sets, idx.byRepo[strings.ToLower(filter.Repo)])
+	}
+	if filter.Branch != "" {
+		sets = append(sets, idx.byBranch[filter.Branch])
+	}
+	for _, term := range tokenize(filter.Query) {
+		sets = append(sets, idx.byTerm[term])
+	}
+	if len(filter.Types) > 0 {
+		// Any of the types matches
+		types := make(idSet)
+		for _, commitType := range filter.Types {
+			for id := range idx.byType[strings.ToLower(commitType)] {
+				types.add(id)
+			}
+		}
+		sets = append(sets, types)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	// Intersect starting from the smallest set
+	smallest := 0
+	for i, set := range sets {
+		if len(set) < len(sets[smallest]) {
+			smallest = i
+		}
+	}
+
+	result := make(idSet, len(sets[smallest]))
+	for id := range sets[smallest] {
+		matches := true
+		for i, set := range sets {
+			if i == smallest {
+				continue
+			}
+			if _, ok := set[id]; !ok {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			result.add(id)
+		}
+	}
+	return result
+}
+
+// excluded reports whether the flags of the filter reject a commit
+func (idx *commitIndex) excluded(id string, filter CommitFilter) bool {
+	if _, ok := idx.private[id]; ok && !filter.IncludePrivate {
+		return true
+	}
+	if _, ok := idx.merges[id]; ok && filter.ExcludeMerges {
+		return true
+	}
+	return false
+}
+
+func isMergeCommit(message string) bool {
+	return strings.HasPrefix(message, "Merge pull request ") ||
+		strings.HasPrefix(message, "Merge branch ") ||
+		strings.HasPrefix(message, "Merge remote-tracking branch ")
+}
+
+// tokenize splits text into lowercase words
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(words))
+	terms := words[:0]
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
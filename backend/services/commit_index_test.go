@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+func TestGetAllCommitsFromCacheFilters(t *testing.T) {
+	base := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	at := func(days int) string { return base.AddDate(0, 0, days).Format(time.RFC3339) }
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	cache.Update([]models.Commit{
+		{ID: "a", RepoName: "gordon", Message: "feat: add Traefik routing", Timestamp: at(0), Branches: []string{"main"}},
+		{ID: "b", RepoName: "gordon", Message: "Merge pull request #4 from bnema/routing", Timestamp: at(1)},
+		{ID: "c", RepoName: "gart", Message: "fix: routing of dotfiles", Timestamp: at(2), Branches: []string{"main", "feat/dotfiles"}},
+		{ID: "d", RepoName: "secret", Message: "feat: routing secret", Timestamp: at(3), IsPrivate: true, Branches: []string{"main"}},
+		{ID: "e", RepoName: "gart", Message: "chore: bump deps", Timestamp: at(4)},
+	})
+	// Updating a commit must drop it from the postings of its old message
+	cache.Update([]models.Commit{
+		{ID: "e", RepoName: "gart", Message: "chore: update dependencies", Timestamp: at(4)},
+	})
+
+	tests := []struct {
+		name     string
+		filter   func(f *CommitFilter)
+		expected []string
+	}{
+		{"no filter", func(f *CommitFilter) {}, []string{"e", "d", "c", "b", "a"}},
+		{"repo", func(f *CommitFilter) { f.Repo = "Gordon" }, []string{"b", "a"}},
+		{"private repo names are not searchable", func(f *CommitFilter) { f.Repo = "secret" }, []string{}},
+		{"query", func(f *CommitFilter) { f.Query = "routing" }, []string{"c", "b", "a"}},
+		{"query with every word", func(f *CommitFilter) { f.Query = "FIX routing" }, []string{"c"}},
+		{"query on updated message", func(f *CommitFilter) { f.Query = "bump" }, []string{}},
+		{"repo and query", func(f *CommitFilter) { f.Repo = "gart"; f.Query = "dependencies" }, []string{"e"}},
+		{"type", func(f *CommitFilter) { f.Types = []string{"feat"} }, []string{"a"}},
+		{"any of several types", func(f *CommitFilter) { f.Types = []string{"FIX", "chore"} }, []string{"e", "c"}},
+		{"type and query", func(f *CommitFilter) { f.Types = []string{"fix", "feat"}; f.Query = "dotfiles" }, []string{"c"}},
+		{"branch", func(f *CommitFilter) { f.Branch = "feat/dotfiles" }, []string{"c"}},
+		{"private branches are not searchable", func(f *CommitFilter) { f.Branch = "main" }, []string{"c", "a"}},
+		{"exclude merges", func(f *CommitFilter) { f.Repo = "gordon"; f.ExcludeMerges = true }, []string{"a"}},
+		{"exclude private", func(f *CommitFilter) { f.IncludePrivate = false }, []string{"e", "c", "b", "a"}},
+		{"date range", func(f *CommitFilter) {
+			f.Since = base.AddDate(0, 0, 1)
+			f.Until = base.AddDate(0, 0, 3)
+		}, []string{"d", "c", "b"}},
+	}
+
+	for _, tt := range tests {
+		filter := DefaultCommitFilter()
+		tt.filter(&filter)
+
+		commits, totalCount, err := GetAllCommitsFromCache(1, 10, filter)
+		if err != nil {
+			t.Fatalf("[%s] GetAllCommitsFromCache returned an error: %v", tt.name, err)
+		}
+		if totalCount != len(tt.expected) {
+			t.Errorf("[%s] Expected %d commits, got %d", tt.name, len(tt.expected), totalCount)
+			continue
+		}
+		for i, id := range tt.expected {
+			// Private commits come back with a pseudo ID
+			if id == "d" {
+				if !commits[i].IsPrivate {
+					t.Errorf("[%s] Expected commit %d to be the private one", tt.name, i)
+				}
+				continue
+			}
+			if commits[i].ID != id {
+				t.Errorf("[%s] Expected commit %d to be %s, got %s", tt.name, i, id, commits[i].ID)
+			}
+		}
+	}
+}
+
+func TestCommitIndexKeepsFeedOrderOnIncrementalUpdates(t *testing.T) {
+	base := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	commitAt := func(id string, minutes int) models.Commit {
+		return models.Commit{ID: id, RepoName: "gordon", Message: "commit " + id, Timestamp: base.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339)}
+	}
+
+	idx := newCommitIndex()
+	var initial []models.Commit
+	for i := 0; i < 40; i++ {
+		initial = append(initial, commitAt(fmt.Sprintf("c%02d", i), i*2))
+	}
+	idx.addAll(initial)
+
+	// Small batches are inserted in place: a new commit and a moved one
+	idx.addAll([]models.Commit{commitAt("new", 15), commitAt("c10", 100)})
+
+	if len(idx.ordered) != 41 || len(idx.entries) != 41 {
+		t.Fatalf("Expected 41 indexed commits, got %d ordered and %d entries", len(idx.ordered), len(idx.entries))
+	}
+	for i := 1; i < len(idx.ordered); i++ {
+		if !idx.ordered[i-1].before(idx.ordered[i]) {
+			t.Fatalf("Expected feed order, %s is listed before %s", idx.ordered[i-1].commit.ID, idx.ordered[i].commit.ID)
+		}
+	}
+	if idx.ordered[0].commit.ID != "c10" {
+		t.Errorf("Expected the moved commit first, got %s", idx.ordered[0].commit.ID)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"portfolio-backend/config"
+
+	"github.com/google/go-github/v63/github"
+)
+
+// ContentFile describes a file exposed by a content provider
+type ContentFile struct {
+	Name string
+	Path string
+	// SHA is the git blob hash of the file content
+	SHA string
+}
+
+// ContentProvider lists and reads the markdown files describing projects
+type ContentProvider interface {
+	ListFiles(ctx context.Context) ([]ContentFile, error)
+	ReadFile(ctx context.Context, file ContentFile) ([]byte, error)
+}
+
+var contentProvider ContentProvider
+
+// InitContentProvider selects where project content is read from
+func InitContentProvider(cfg *config.Config) error {
+	projectRefreshTimeout = cfg.ProjectRefreshTimeout
+	switch cfg.ContentSource {
+	case "", "github":
+		contentProvider = NewGitHubContentProvider(cfg.ContentOwner, cfg.ContentRepo, cfg.ContentPath)
+	case "local":
+		if _, err := os.Stat(cfg.ContentDir); err != nil {
+			return fmt.Errorf("content directory is not readable: %w", err)
+		}
+		contentProvider = NewLocalContentProvider(cfg.ContentDir)
+	default:
+		return fmt.Errorf("unknown content source: %s", cfg.ContentSource)
+	}
+	return nil
+}
+
+func GetContentProvider() ContentProvider {
+	return contentProvider
+}
+
+// GitHubContentProvider reads project files through the GitHub API. The directory
+// listing is revalidated with its ETag, so an unchanged directory costs no rate limit.
+type GitHubContentProvider struct {
+	owner string
+	repo  string
+	dir   string
+
+	etag  string
+	files []ContentFile
+	mutex sync.Mutex
+}
+
+func NewGitHubContentProvider(owner, repo, dir string) *GitHubContentProvider {
+	return &GitHubContentProvider{owner: owner, repo: repo, dir: dir}
+}
+
+func (p *GitHubContentProvider) ListFiles(ctx context.Context) ([]ContentFile, error) {
+	client := GetGitHubClient()
+	if client == nil {
+		return nil, errors.New("GitHub client is not initialized")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	escapedPath := (&url.URL{Path: strings.TrimSuffix(p.dir, "/")}).String()
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/contents/%s", p.owner, p.repo, escapedPath), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+
+	var directoryContents []*github.RepositoryContent
+	resp, err := client.Do(ctx, req, &directoryContents)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return p.files, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch directory contents: %w", err)
+	}
+
+	files := make([]ContentFile, 0, len(directoryContents))
+	for _, entry := range directoryContents {
+		if entry.GetType() != "file" {
+			continue
+		}
+		files = append(files, ContentFile{
+			Name: entry.GetName(),
+			Path: entry.GetPath(),
+			SHA:  entry.GetSHA(),
+		})
+	}
+
+	p.etag = resp.Header.Get("ETag")
+	p.files = files
+	return files, nil
+}
+
+// ReadFile fetches the blob matching the listed SHA, so content and SHA always agree
+func (p *GitHubContentProvider) ReadFile(ctx context.Context, file ContentFile) ([]byte, error) {
+	client := GetGitHubClient()
+	if client == nil {
+		return nil, errors.New("GitHub client is not initialized")
+	}
+
+	if file.SHA != "" {
+		content, _, err := client.Git.GetBlobRaw(ctx, p.owner, p.repo, file.SHA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch file content for %s: %w", file.Name, err)
+		}
+		return content, nil
+	}
+
+	fileContent, _, _, err := client.Repositories.GetContents(ctx, p.owner, p.repo, file.Path, &github.RepositoryContentGetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch file content for %s: %w", file.Name, err)
+	}
+
+	content, err := fileContent.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode content for %s: %w", file.Name, err)
+	}
+	return []byte(content), nil
+}
+
+// FSContentProvider reads project files from a file system, such as a local
+// directory or an embed.FS baked into the binary
+type FSContentProvider struct {
+	fsys fs.FS
+	dir  string
+}
+
+// NewFSContentProvider serves the files found in dir of the given file system
+func NewFSContentProvider(fsys fs.FS, dir string) *FSContentProvider {
+	return &FSContentProvider{fsys: fsys, dir: dir}
+}
+
+// NewLocalContentProvider serves the files of a directory on disk
+func NewLocalContentProvider(dir string) *FSContentProvider {
+	return NewFSContentProvider(os.DirFS(dir), ".")
+}
+
+func (p *FSContentProvider) ListFiles(ctx context.Context) ([]ContentFile, error) {
+	entries, err := fs.ReadDir(p.fsys, p.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content directory: %w", err)
+	}
+
+	var files []ContentFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		filePath := path.Join(p.dir, entry.Name())
+		content, err := fs.ReadFile(p.fsys, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		files = append(files, ContentFile{
+			Name: entry.Name(),
+			Path: filePath,
+			SHA:  gitBlobSHA(content),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+func (p *FSContentProvider) ReadFile(ctx context.Context, file ContentFile) ([]byte, error) {
+	content, err := fs.ReadFile(p.fsys, file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content for %s: %w", file.Name, err)
+	}
+	return content, nil
+}
+
+// gitBlobSHA hashes content the way git does, so local files get the same SHA as on GitHub
+func gitBlobSHA(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
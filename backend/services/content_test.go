@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFetchProjectsContentFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"projects/gart.md":   {Data: []byte("[project_origin]: github\n#Gart\nDotfile manager")},
+		"projects/notes.txt": {Data: []byte("not a project")},
+	}
+
+	originalProvider := contentProvider
+	contentProvider = NewFSContentProvider(fsys, "projects")
+	defer func() { contentProvider = originalProvider }()
+
+	originalCache := projectCache
+	projectCache = NewProjectCache()
+	defer func() { projectCache = originalCache }()
+
+	projects, err := FetchProjectsContent(context.Background())
+	if err != nil {
+		t.Fatalf("FetchProjectsContent returned an error: %v", err)
+	}
+
+	if len(projects) != 1 {
+		t.Fatalf("Expected 1 project, got %d", len(projects))
+	}
+
+	project := projects[0]
+	if project.Title != "gart" || project.Slug != "gart" || project.ProjectOrigin != "github" {
+		t.Errorf("Unexpected project metadata %+v", project)
+	}
+	if project.Content != "#Gart\nDotfile manager" {
+		t.Errorf("Unexpected project content %q", project.Content)
+	}
+}
+
+func TestGitBlobSHAMatchesGit(t *testing.T) {
+	// Same value as `echo hello | git hash-object --stdin`
+	if sha := gitBlobSHA([]byte("hello\n")); sha != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Unexpected blob SHA %s", sha)
+	}
+}
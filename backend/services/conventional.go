@@ -0,0 +1,41 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"portfolio-backend/models"
+)
+
+// conventionalHeader matches headers such as "feat(api)!: add cursors"
+var conventionalHeader = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()]*)\))?(!)?: *(.+)$`)
+
+// applyConventionalCommit splits the message of a commit into the Conventional Commits
+// fields. Messages that do not follow the convention only get a subject and a body.
+func applyConventionalCommit(commit *models.Commit) {
+	message := strings.ReplaceAll(commit.Message, "\r\n", "\n")
+	header, body, _ := strings.Cut(message, "\n")
+	header = strings.TrimSpace(header)
+
+	commit.Subject = header
+	commit.Body = strings.TrimSpace(body)
+
+	match := conventionalHeader.FindStringSubmatch(header)
+	if match == nil {
+		return
+	}
+	commit.Type = strings.ToLower(match[1])
+	commit.Scope = strings.TrimSpace(match[2])
+	commit.Subject = strings.TrimSpace(match[4])
+	commit.Breaking = match[3] == "!" || hasBreakingFooter(commit.Body)
+}
+
+// hasBreakingFooter reports whether the body has a BREAKING CHANGE footer
+func hasBreakingFooter(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			return true
+		}
+	}
+	return false
+}
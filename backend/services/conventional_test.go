@@ -0,0 +1,33 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"portfolio-backend/models"
+)
+
+func TestApplyConventionalCommit(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected models.Commit
+	}{
+		{"feat: add cursors", models.Commit{Type: "feat", Subject: "add cursors"}},
+		{"fix(api): handle empty pages\n\nPages past the end returned null.", models.Commit{Type: "fix", Scope: "api", Subject: "handle empty pages", Body: "Pages past the end returned null."}},
+		{"Refactor(cache)!: drop the legacy store", models.Commit{Type: "refactor", Scope: "cache", Breaking: true, Subject: "drop the legacy store"}},
+		{"chore: bump deps\r\n\r\nBREAKING CHANGE: requires Go 1.23", models.Commit{Type: "chore", Breaking: true, Subject: "bump deps", Body: "BREAKING CHANGE: requires Go 1.23"}},
+		{"Merge pull request #4 from bnema/routing\n\nfeat: routing", models.Commit{Subject: "Merge pull request #4 from bnema/routing", Body: "feat: routing"}},
+		{"Update README.md", models.Commit{Subject: "Update README.md"}},
+		{"feat(a)(b): not a scope", models.Commit{Subject: "feat(a)(b): not a scope"}},
+	}
+
+	for _, tt := range tests {
+		commit := models.Commit{Message: tt.message}
+		applyConventionalCommit(&commit)
+
+		tt.expected.Message = tt.message
+		if !reflect.DeepEqual(commit, tt.expected) {
+			t.Errorf("Parsing %q: expected %+v, got %+v", tt.message, tt.expected, commit)
+		}
+	}
+}
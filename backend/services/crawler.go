@@ -0,0 +1,602 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v63/github"
+)
+
+// Crawl states reported by the sync status
+const (
+	CrawlIdle    = "idle"
+	CrawlRunning = "running"
+	CrawlDone    = "done"
+	CrawlFailed  = "failed"
+)
+
+// ErrCrawlInProgress is returned when a crawl is started while another one runs
+var ErrCrawlInProgress = errors.New("a repository crawl is already running")
+
+// Crawler fetches the commits of every repository with a bounded pool of workers
+type Crawler struct {
+	concurrency int
+	repoTimeout time.Duration
+	// branches are the patterns of the branches synced besides the default one
+	branches []string
+
+	mutex      sync.Mutex
+	status     models.CrawlStatus
+	inProgress map[string]bool
+	failed     []string
+}
+
+// crawlRateReserve is the part of the core budget of a token a crawl leaves to the API
+// handlers and project content, repositories of an account below it wait for the next crawl
+const crawlRateReserve = 100
+
+// syncOverlap widens the since window of a repository sync, commits already cached are deduplicated
+const syncOverlap = time.Hour
+
+// crawler runs with the default settings until InitCrawler applies the configured ones
+var crawler = newConfiguredCrawler(config.Defaults())
+
+func NewCrawler(concurrency int, repoTimeout time.Duration) *Crawler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Crawler{
+		concurrency: concurrency,
+		repoTimeout: repoTimeout,
+		status:      models.CrawlStatus{State: CrawlIdle},
+		inProgress:  make(map[string]bool),
+	}
+}
+
+// InitCrawler applies the configured crawl concurrency, per repository timeout and branches
+func InitCrawler(cfg *config.Config) {
+	crawler = newConfiguredCrawler(cfg)
+}
+
+func newConfiguredCrawler(cfg *config.Config) *Crawler {
+	c := NewCrawler(cfg.CrawlConcurrency, cfg.CrawlRepoTimeout)
+	c.branches = cfg.Branches
+	return c
+}
+
+// GetCrawlStatus returns the progress of the running crawl, or the outcome of the last one
+func GetCrawlStatus() models.CrawlStatus {
+	return crawler.Status()
+}
+
+func (c *Crawler) Status() models.CrawlStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	status := c.status
+	// Only public repository names are listed, private ones are counted
+	status.InProgress = make([]string, 0, len(c.inProgress))
+	for name := range c.inProgress {
+		status.InProgress = append(status.InProgress, name)
+	}
+	sort.Strings(status.InProgress)
+	status.FailedRepos = append([]string{}, c.failed...)
+	return status
+}
+
+// Sync lists the repositories of every account and fetches, concurrently, the commits of
+// those pushed to since their last sync. Only the commits of the accounts are kept when
+// they have identities to match. publish receives the commits of each
+// repository as soon as it is done. Renamed repositories have their cached commits
+// rewritten and deleted ones are dropped. Repositories failing on their own keep their
+// previous state and are retried by the next sync, while a rate limit stops the whole sync
+// since the remaining repositories would fail the same way.
+func (c *Crawler) Sync(ctx context.Context, accounts []*githubAccount, publish func([]models.Commit)) error {
+	c.mutex.Lock()
+	if c.status.State == CrawlRunning {
+		c.mutex.Unlock()
+		return ErrCrawlInProgress
+	}
+	c.status = models.CrawlStatus{State: CrawlRunning, StartedAt: time.Now().UTC().Format(time.RFC3339)}
+	c.inProgress = make(map[string]bool)
+	c.failed = nil
+	c.mutex.Unlock()
+
+	err := c.sync(ctx, accounts, publish)
+
+	c.mutex.Lock()
+	c.status.State = CrawlDone
+	if err != nil {
+		c.status.State = CrawlFailed
+		c.status.Error = err.Error()
+	}
+	c.status.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+	c.mutex.Unlock()
+	return err
+}
+
+// repoSync is a repository to sync along with the account listing it and its previous
+// state, nil for new repositories
+type repoSync struct {
+	repo     *github.Repository
+	account  *githubAccount
+	previous *models.RepoSyncState
+}
+
+func (c *Crawler) sync(ctx context.Context, accounts []*githubAccount, publish func([]models.Commit)) error {
+	startedAt := time.Now().UTC()
+	attribution, err := newCommitAttribution(ctx, accounts)
+	if err != nil {
+		return err
+	}
+	repos, excluded, err := listAccountRepos(ctx, accounts)
+	if err != nil {
+		return err
+	}
+	c.count(func(status *models.CrawlStatus) { status.ReposExcluded = excluded })
+
+	previousStates := cache.RepoStates()
+	states := make(map[int64]models.RepoSyncState, len(repos))
+	var pending []repoSync
+	for _, listed := range repos {
+		repo := listed.repo
+		previous, known := previousStates[repo.GetID()]
+		delete(previousStates, repo.GetID())
+		if !known {
+			pending = append(pending, listed)
+			continue
+		}
+
+		if previous.Owner != repo.GetOwner().GetLogin() || previous.Name != repo.GetName() || previous.IsPrivate != repo.GetPrivate() {
+			previous = renameRepo(previous, repo)
+			c.count(func(status *models.CrawlStatus) { status.ReposRenamed++ })
+		}
+		states[repo.GetID()] = previous
+
+		unchanged := previous.PushedAt != "" && previous.PushedAt == formatTimestamp(repo.PushedAt) && previous.DefaultBranch == repo.GetDefaultBranch()
+		// Branches matching newly configured patterns have not been synced yet
+		if unchanged && slices.Equal(previous.BranchPatterns, c.branches) {
+			c.count(func(status *models.CrawlStatus) { status.ReposUnchanged++ })
+			continue
+		}
+		listed.previous = &previous
+		pending = append(pending, listed)
+	}
+
+	// Repositories left over were deleted, transferred away or are now excluded by the rules
+	for _, gone := range previousStates {
+		var ids []string
+		for _, commit := range cache.commitsOfRepo(gone.Owner, gone.Name) {
+			ids = append(ids, commit.ID)
+		}
+		cache.Remove(ids)
+		c.count(func(status *models.CrawlStatus) { status.ReposDeleted++ })
+	}
+
+	c.count(func(status *models.CrawlStatus) { status.ReposTotal = len(repos) })
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stopErr error
+	var stopOnce sync.Once
+	stop := func(err error) {
+		stopOnce.Do(func() {
+			stopErr = err
+			cancel()
+		})
+	}
+
+	var statesMutex sync.Mutex
+	jobs := make(chan repoSync)
+	var wg sync.WaitGroup
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				repo := job.repo
+				c.started(repo)
+				commits, heads, err := c.syncRepo(ctx, job, attribution)
+				if err != nil {
+					if isRateLimitError(err) {
+						stop(fmt.Errorf("rate limited while fetching %s: %w", repo.GetName(), err))
+					} else if ctx.Err() == nil {
+						log.Warn(fmt.Sprintf("failed to fetch commits from repository: %s", err))
+					}
+					c.finished(repo, 0, err)
+					continue
+				}
+				if len(commits) > 0 {
+					publish(commits)
+				}
+
+				state := models.RepoSyncState{
+					ID:             repo.GetID(),
+					Owner:          repo.GetOwner().GetLogin(),
+					Name:           repo.GetName(),
+					IsPrivate:      repo.GetPrivate(),
+					DefaultBranch:  repo.GetDefaultBranch(),
+					LastSyncedAt:   startedAt.Format(time.RFC3339),
+					PushedAt:       formatTimestamp(repo.PushedAt),
+					LastSeenSHA:    heads[repo.GetDefaultBranch()],
+					Branches:       heads,
+					BranchPatterns: c.branches,
+				}
+				statesMutex.Lock()
+				states[repo.GetID()] = state
+				statesMutex.Unlock()
+
+				if job.previous == nil {
+					c.count(func(status *models.CrawlStatus) { status.ReposNew++ })
+				}
+				c.finished(repo, len(commits), nil)
+			}
+		}()
+	}
+
+	deferred := 0
+send:
+	for _, job := range pending {
+		// A repository costs at least one request, more with paging and branches
+		if remaining, known := job.account.rateLimiter().Remaining(RateResourceCore); known && remaining < crawlRateReserve {
+			deferred++
+			continue
+		}
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if deferred > 0 {
+		// Known repositories keep their previous state, new ones are still unknown
+		log.Warn("GitHub rate limit budget low, repositories deferred to the next crawl", "repos", deferred)
+		c.count(func(status *models.CrawlStatus) { status.ReposDeferred = deferred })
+	}
+
+	// Progress is kept even when the sync was cut short
+	saved := make([]models.RepoSyncState, 0, len(states))
+	for _, state := range states {
+		saved = append(saved, state)
+	}
+	cache.SaveRepoStates(saved)
+
+	if stopErr != nil {
+		return stopErr
+	}
+	return ctx.Err()
+}
+
+// syncRepo fetches the commits of a repository made since its last sync on every synced
+// branch, within the per repository timeout. Commits found on several branches are
+// returned once with all their branch names. It also returns the newest commit of each
+// branch. Known branches are fetched since their last sync, new ones until they reach
+// a commit already cached, which is where they forked off.
+func (c *Crawler) syncRepo(ctx context.Context, job repoSync, attribution *commitAttribution) ([]models.Commit, map[string]string, error) {
+	if c.repoTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.repoTimeout)
+		defer cancel()
+	}
+
+	repo := job.repo
+	client := job.account.githubClient()
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	branches, err := c.branchesToSync(ctx, client, repo)
+	if isEmptyRepoError(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var since time.Time
+	previousHeads := make(map[string]string)
+	if previous := job.previous; previous != nil {
+		if syncedAt, err := time.Parse(time.RFC3339, previous.LastSyncedAt); err == nil {
+			// Commit dates come from the committer's clock, leave some slack
+			since = syncedAt.Add(-syncOverlap)
+		}
+		for branch, sha := range previous.Branches {
+			previousHeads[branch] = sha
+		}
+		// States saved before branches were tracked only know the default branch
+		if len(previousHeads) == 0 && previous.LastSeenSHA != "" {
+			previousHeads[previous.DefaultBranch] = previous.LastSeenSHA
+		}
+	}
+
+	var commits []models.Commit
+	positions := make(map[string]int)
+	// seen also holds the commits of other authors, which are not kept
+	seen := make(map[string]bool)
+	heads := make(map[string]string, len(branches))
+	for _, branch := range branches {
+		branchSince := since
+		stop := func(sha string) bool { return sha == previousHeads[branch] }
+		if _, known := previousHeads[branch]; !known {
+			// Nothing of a new repository is cached yet, its default branch is read in full
+			branchSince = time.Time{}
+			stop = func(sha string) bool {
+				return seen[sha] || (job.previous != nil && cache.has(sha))
+			}
+		}
+
+		branchCommits, err := listRepoCommits(ctx, client, owner, name, branch, branchSince, stop)
+		if isEmptyRepoError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(branchCommits) > 0 {
+			heads[branch] = branchCommits[0].GetSHA()
+		} else if head := previousHeads[branch]; head != "" {
+			heads[branch] = head
+		}
+		for _, commit := range branchCommits {
+			sha := commit.GetSHA()
+			if i, ok := positions[sha]; ok {
+				commits[i].Branches = append(commits[i].Branches, branch)
+				continue
+			}
+			if seen[sha] {
+				continue
+			}
+			seen[sha] = true
+
+			account, ok := attribution.attribute(commit, job.account)
+			if !ok {
+				continue
+			}
+			newCommit := newGitHubCommit(commit, name, branch, repo.GetPrivate())
+			newCommit.Account = account
+			positions[sha] = len(commits)
+			commits = append(commits, newCommit)
+		}
+	}
+	return commits, heads, nil
+}
+
+// branchesToSync returns the default branch of a repository followed by the other
+// branches matching the configured patterns
+func (c *Crawler) branchesToSync(ctx context.Context, client *github.Client, repo *github.Repository) ([]string, error) {
+	branches := []string{repo.GetDefaultBranch()}
+	if len(c.branches) == 0 {
+		return branches, nil
+	}
+
+	opts := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		list, resp, err := client.Repositories.ListBranches(ctx, repo.GetOwner().GetLogin(), repo.GetName(), opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range list {
+			if name := branch.GetName(); name != repo.GetDefaultBranch() && c.syncsBranch(repo.GetDefaultBranch(), name) {
+				branches = append(branches, name)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return branches, nil
+}
+
+// syncsBranch reports whether the commits of a branch are synced, the default branch always is
+func (c *Crawler) syncsBranch(defaultBranch, branch string) bool {
+	return branch == defaultBranch || matchBranch(c.branches, branch)
+}
+
+// matchBranch reports whether a branch matches any of the glob patterns, "*" matching
+// every branch, slashes included
+func matchBranch(patterns []string, branch string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyRepoError reports whether GitHub answered 409 Conflict, which it does for
+// repositories without any commit
+func isEmptyRepoError(err error) bool {
+	var errorResponse *github.ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.Response.StatusCode == http.StatusConflict
+}
+
+// renameRepo rewrites the cached commits of a repository that was renamed, transferred
+// or changed visibility, and returns its updated state
+func renameRepo(previous models.RepoSyncState, repo *github.Repository) models.RepoSyncState {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	oldPrefix := fmt.Sprintf("https://github.com/%s/%s/", previous.Owner, previous.Name)
+	newPrefix := fmt.Sprintf("https://github.com/%s/%s/", owner, name)
+
+	commits := cache.commitsOfRepo(previous.Owner, previous.Name)
+	for i, commit := range commits {
+		commit.RepoName = name
+		commit.URL = newPrefix + commit.URL[len(oldPrefix):]
+		commit.IsPrivate = repo.GetPrivate()
+		commits[i] = commit
+	}
+	if len(commits) > 0 {
+		cache.Merge(commits)
+	}
+
+	moved := previous.Owner != owner || previous.Name != name
+	switch {
+	case moved && previous.IsPrivate != repo.GetPrivate():
+		log.Info("Repository renamed and visibility changed", "commits", len(commits), "private", repo.GetPrivate())
+	case moved:
+		log.Info("Repository renamed", "commits", len(commits))
+	default:
+		log.Info("Repository visibility changed", "commits", len(commits), "private", repo.GetPrivate())
+	}
+	previous.Owner = owner
+	previous.Name = name
+	previous.IsPrivate = repo.GetPrivate()
+	return previous
+}
+
+func (c *Crawler) count(update func(status *models.CrawlStatus)) {
+	c.mutex.Lock()
+	update(&c.status)
+	c.mutex.Unlock()
+}
+
+func formatTimestamp(t *github.Timestamp) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (c *Crawler) started(repo *github.Repository) {
+	if repo.GetPrivate() {
+		return
+	}
+	c.mutex.Lock()
+	c.inProgress[repo.GetName()] = true
+	c.mutex.Unlock()
+}
+
+func (c *Crawler) finished(repo *github.Repository, commits int, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.inProgress, repo.GetName())
+	c.status.ReposDone++
+	c.status.CommitsFetched += commits
+	if err != nil {
+		c.status.ReposFailed++
+		if !repo.GetPrivate() {
+			c.failed = append(c.failed, repo.GetName())
+		}
+	}
+}
+
+// listRepos lists the public and private repositories of the authenticated user with the
+// given affiliations, such as owner or organization_member
+func listRepos(ctx context.Context, client *github.Client, affiliation string) ([]*github.Repository, error) {
+	var allRepos []*github.Repository
+	opts := &github.RepositoryListByAuthenticatedUserOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+		Affiliation: affiliation,
+	}
+	for {
+		repos, resp, err := client.Repositories.ListByAuthenticatedUser(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		allRepos = append(allRepos, repos...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allRepos, nil
+}
+
+// listAccountRepos lists the repositories of every account allowed by the repository rules,
+// along with the number of excluded ones. A repository several accounts can read, such as
+// one of a shared organization, is synced once by the first of them. The sync fails when
+// any listing fails, its repositories would be taken as deleted otherwise.
+func listAccountRepos(ctx context.Context, accounts []*githubAccount) ([]repoSync, int, error) {
+	var repos []repoSync
+	var excluded int
+	listed := make(map[int64]bool)
+	for _, account := range accounts {
+		accountRepos, err := listRepos(ctx, account.githubClient(), account.affiliation)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list repositories of account %q: %w", account.name, err)
+		}
+		for _, repo := range accountRepos {
+			if !allowsGitHubRepo(repo) {
+				excluded++
+				continue
+			}
+			if !listed[repo.GetID()] {
+				listed[repo.GetID()] = true
+				repos = append(repos, repoSync{repo: repo, account: account})
+			}
+		}
+	}
+	return repos, excluded, nil
+}
+
+// allowsGitHubRepo reports whether the repository rules allow a GitHub repository
+func allowsGitHubRepo(repo *github.Repository) bool {
+	return repoRules.Allows(repo.GetOwner().GetLogin()+"/"+repo.GetName(), repo.GetArchived(), repo.GetFork())
+}
+
+// commitAttribution matches commits to the account that authored them
+type commitAttribution struct {
+	accounts []*githubAccount
+	// filter drops the commits of other authors, it is set once an account has identities
+	// to match, so commits of collaborators in owned repositories are kept otherwise
+	filter bool
+}
+
+// newCommitAttribution resolves the login of the accounts when commits are filtered
+func newCommitAttribution(ctx context.Context, accounts []*githubAccount) (*commitAttribution, error) {
+	attribution := &commitAttribution{accounts: accounts}
+	for _, account := range accounts {
+		if len(account.emails) > 0 || account.affiliation != "owner" {
+			attribution.filter = true
+		}
+	}
+	if !attribution.filter {
+		return attribution, nil
+	}
+
+	for _, account := range accounts {
+		if account.login != "" {
+			continue
+		}
+		user, _, err := account.githubClient().Users.Get(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the user of account %q: %w", account.name, err)
+		}
+		account.login = user.GetLogin()
+	}
+	return attribution, nil
+}
+
+// attribute returns the name of the account a commit belongs to and whether it is kept.
+// Without filtering commits belong to the account syncing the repository.
+func (a *commitAttribution) attribute(commit *github.RepositoryCommit, syncing *githubAccount) (string, bool) {
+	if !a.filter {
+		return syncing.name, true
+	}
+	login := commit.GetAuthor().GetLogin()
+	email := commit.GetCommit().GetAuthor().GetEmail()
+	for _, account := range a.accounts {
+		if (login != "" && strings.EqualFold(login, account.login)) || matchAuthor(email, account.emails...) {
+			return account.name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/google/go-github/v63/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestUpdateCommitCacheCrawlsEveryRepository(t *testing.T) {
+	owner := &github.User{Login: github.String("testuser")}
+	commitAt := func(sha string, date time.Time) *github.RepositoryCommit {
+		return &github.RepositoryCommit{
+			SHA:     github.String(sha),
+			HTMLURL: github.String("https://github.com/testuser/repo/commit/" + sha),
+			Commit: &github.Commit{
+				Message: github.String("commit " + sha),
+				Author:  &github.CommitAuthor{Date: &github.Timestamp{Time: date}},
+			},
+		}
+	}
+	date := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetUserRepos,
+			[]*github.Repository{
+				{ID: github.Int64(1), Name: github.String("gordon"), Owner: owner},
+				{ID: github.Int64(2), Name: github.String("secret"), Owner: owner, Private: github.Bool(true)},
+				{ID: github.Int64(3), Name: github.String("empty"), Owner: owner},
+				{ID: github.Int64(4), Name: github.String("broken"), Owner: owner},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch strings.Split(r.URL.Path, "/")[3] {
+				case "gordon":
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{commitAt("a", date), commitAt("b", date.Add(time.Hour))})
+				case "secret":
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{commitAt("c", date)})
+				case "empty":
+					w.WriteHeader(http.StatusConflict)
+					w.Write([]byte(`{"message": "Git Repository is empty."}`))
+				default:
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(`{"message": "Server Error"}`))
+				}
+			}),
+		),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(mockedHTTPClient)
+	defer func() { githubClient = originalClient }()
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	originalCrawler := crawler
+	crawler = NewCrawler(2, time.Minute)
+	defer func() { crawler = originalCrawler }()
+
+	before := time.Now().UTC()
+	if err := UpdateCommitCache(); err != nil {
+		t.Fatalf("UpdateCommitCache returned an error: %v", err)
+	}
+
+	if len(cache.commits) != 3 {
+		t.Fatalf("Expected 3 cached commits, got %d", len(cache.commits))
+	}
+	if !cache.commits["c"].IsPrivate || cache.commits["a"].RepoName != "gordon" {
+		t.Errorf("Unexpected commits %+v", cache.commits)
+	}
+	if lastUpdated := cache.GetLastUpdated(); lastUpdated.Before(before) || lastUpdated.After(time.Now()) {
+		t.Errorf("Expected the last update to be the start of the crawl, got %s", lastUpdated)
+	}
+
+	status := GetCrawlStatus()
+	if status.State != CrawlDone || status.ReposTotal != 4 || status.ReposDone != 4 || status.CommitsFetched != 3 {
+		t.Errorf("Unexpected crawl status %+v", status)
+	}
+	if status.ReposFailed != 1 || len(status.FailedRepos) != 1 || status.FailedRepos[0] != "broken" {
+		t.Errorf("Expected only the broken repository to fail, got %+v", status)
+	}
+	if len(status.InProgress) != 0 || status.ReposNew != 3 {
+		t.Errorf("Expected a finished crawl, got %+v", status)
+	}
+}
+
+func TestSyncHandlesRenamedAndDeletedRepos(t *testing.T) {
+	owner := &github.User{Login: github.String("testuser")}
+	pushedAt := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetUserRepos,
+			[]*github.Repository{
+				{ID: github.Int64(1), Name: github.String("gordon-v2"), Owner: owner, DefaultBranch: github.String("main"), PushedAt: &github.Timestamp{Time: pushedAt}},
+				{ID: github.Int64(3), Name: github.String("gart"), Owner: owner, DefaultBranch: github.String("main"), PushedAt: &github.Timestamp{Time: pushedAt}},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("Expected no commit listing for unchanged repositories, got %s", r.URL.Path)
+				json.NewEncoder(w).Encode([]*github.RepositoryCommit{})
+			}),
+		),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(mockedHTTPClient)
+	defer func() { githubClient = originalClient }()
+
+	originalCache := cache
+	defer func() { cache = originalCache }()
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	cache.Update([]models.Commit{
+		{ID: "a", RepoName: "gordon", Message: "feat: a", Timestamp: "2024-08-01T10:00:00Z", URL: "https://github.com/testuser/gordon/commit/a"},
+		{ID: "b", RepoName: "secret", Message: "feat: b", Timestamp: "2024-08-01T10:00:00Z", URL: "https://github.com/testuser/secret/commit/b", IsPrivate: true},
+		{ID: "c", RepoName: "gart", Message: "feat: c", Timestamp: "2024-08-01T10:00:00Z", URL: "https://github.com/testuser/gart/commit/c"},
+	})
+	state := func(id int64, name string) models.RepoSyncState {
+		return models.RepoSyncState{ID: id, Owner: "testuser", Name: name, DefaultBranch: "main", PushedAt: pushedAt.Format(time.RFC3339)}
+	}
+	cache.SaveRepoStates([]models.RepoSyncState{state(1, "gordon"), state(2, "secret"), state(3, "gart")})
+
+	originalCrawler := crawler
+	crawler = NewCrawler(2, time.Minute)
+	defer func() { crawler = originalCrawler }()
+
+	if err := UpdateCommitCache(); err != nil {
+		t.Fatalf("UpdateCommitCache returned an error: %v", err)
+	}
+
+	renamed := cache.commits["a"]
+	if renamed.RepoName != "gordon-v2" || renamed.URL != "https://github.com/testuser/gordon-v2/commit/a" {
+		t.Errorf("Expected the commit to follow the renamed repository, got %+v", renamed)
+	}
+	if _, ok := cache.commits["b"]; ok {
+		t.Error("Expected commits of the deleted repository to be dropped")
+	}
+	if _, ok := cache.commits["c"]; !ok {
+		t.Error("Expected commits of the unchanged repository to be kept")
+	}
+
+	states := cache.RepoStates()
+	if len(states) != 2 || states[1].Name != "gordon-v2" {
+		t.Errorf("Unexpected sync states %+v", states)
+	}
+	status := GetCrawlStatus()
+	if status.ReposRenamed != 1 || status.ReposDeleted != 1 || status.ReposUnchanged != 2 || status.ReposDone != 0 {
+		t.Errorf("Unexpected sync status %+v", status)
+	}
+}
+
+func TestSyncSkipsExcludedRepos(t *testing.T) {
+	owner := &github.User{Login: github.String("testuser")}
+	date := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetUserRepos,
+			[]*github.Repository{
+				{ID: github.Int64(1), Name: github.String("gordon"), Owner: owner, DefaultBranch: github.String("main"), PushedAt: &github.Timestamp{Time: date}},
+				{ID: github.Int64(2), Name: github.String("echo"), Owner: owner, DefaultBranch: github.String("main"), Fork: github.Bool(true)},
+				{ID: github.Int64(3), Name: github.String("old"), Owner: owner, DefaultBranch: github.String("main"), Archived: github.Bool(true)},
+				{ID: github.Int64(4), Name: github.String("go-sandbox"), Owner: owner, DefaultBranch: github.String("main")},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/gordon/commits") {
+					t.Errorf("Expected excluded repositories not to be synced, got %s", r.URL.Path)
+				}
+				json.NewEncoder(w).Encode([]*github.RepositoryCommit{{
+					SHA:     github.String("g1"),
+					HTMLURL: github.String("https://github.com/testuser/gordon/commit/g1"),
+					Commit: &github.Commit{
+						Message: github.String("feat: g1"),
+						Author:  &github.CommitAuthor{Date: &github.Timestamp{Time: date}},
+					},
+				}})
+			}),
+		),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(mockedHTTPClient)
+	defer func() { githubClient = originalClient }()
+
+	originalRules := repoRules
+	defer func() { repoRules = originalRules }()
+	rules, err := NewRepoRules(config.RepoRules{Exclude: []string{"*-sandbox"}, ExcludeArchived: true, ExcludeForks: true})
+	if err != nil {
+		t.Fatalf("NewRepoRules returned an error: %v", err)
+	}
+	repoRules = rules
+
+	originalCache := cache
+	defer func() { cache = originalCache }()
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	// The archived repository was synced before the rules excluded it
+	cache.Update([]models.Commit{{ID: "o1", RepoName: "old", Timestamp: "2024-08-01T10:00:00Z", URL: "https://github.com/testuser/old/commit/o1"}})
+	cache.SaveRepoStates([]models.RepoSyncState{{ID: 3, Owner: "testuser", Name: "old", DefaultBranch: "main"}})
+
+	originalCrawler := crawler
+	crawler = NewCrawler(2, time.Minute)
+	defer func() { crawler = originalCrawler }()
+
+	if err := UpdateCommitCache(); err != nil {
+		t.Fatalf("UpdateCommitCache returned an error: %v", err)
+	}
+
+	if len(cache.commits) != 1 || cache.commits["g1"].RepoName != "gordon" {
+		t.Errorf("Expected only the commits of the allowed repository, got %+v", cache.commits)
+	}
+	if status := GetCrawlStatus(); status.ReposExcluded != 3 || status.ReposTotal != 1 {
+		t.Errorf("Unexpected sync status %+v", status)
+	}
+}
+
+func TestSyncIngestsMatchingBranches(t *testing.T) {
+	owner := &github.User{Login: github.String("testuser")}
+	date := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	commit := func(sha string) *github.RepositoryCommit {
+		return &github.RepositoryCommit{
+			SHA:     github.String(sha),
+			HTMLURL: github.String("https://github.com/testuser/gordon/commit/" + sha),
+			Commit: &github.Commit{
+				Message: github.String("commit " + sha),
+				Author:  &github.CommitAuthor{Date: &github.Timestamp{Time: date}},
+			},
+		}
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetUserRepos,
+			[]*github.Repository{
+				{ID: github.Int64(1), Name: github.String("gordon"), Owner: owner, DefaultBranch: github.String("main"), PushedAt: &github.Timestamp{Time: date}},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposBranchesByOwnerByRepo,
+			[]*github.Branch{{Name: github.String("main")}, {Name: github.String("feat/x")}, {Name: github.String("feat/y")}, {Name: github.String("docs")}},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch branch := r.URL.Query().Get("sha"); branch {
+				case "main":
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{commit("m2"), commit("m1")})
+				case "feat/x":
+					// m2 was fast-forwarded into main
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{commit("m2"), commit("x1")})
+				case "feat/y":
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{commit("y1"), commit("m1"), commit("m0")})
+				default:
+					t.Errorf("Expected branch %s not to be synced", branch)
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{})
+				}
+			}),
+		),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(mockedHTTPClient)
+	defer func() { githubClient = originalClient }()
+
+	originalCache := cache
+	defer func() { cache = originalCache }()
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	cache.Update([]models.Commit{
+		{ID: "m1", RepoName: "gordon", Timestamp: "2024-08-01T10:00:00Z", URL: "https://github.com/testuser/gordon/commit/m1", Branches: []string{"main"}},
+		{ID: "x1", RepoName: "gordon", Timestamp: "2024-08-01T10:00:00Z", URL: "https://github.com/testuser/gordon/commit/x1", Branches: []string{"feat/x"}},
+	})
+	cache.SaveRepoStates([]models.RepoSyncState{{
+		ID: 1, Owner: "testuser", Name: "gordon", DefaultBranch: "main",
+		LastSeenSHA:  "m1",
+		LastSyncedAt: date.Add(-time.Hour).Format(time.RFC3339),
+		Branches:     map[string]string{"main": "m1", "feat/x": "x1"},
+	}})
+
+	originalCrawler := crawler
+	crawler = NewCrawler(1, time.Minute)
+	crawler.branches = []string{"feat/*"}
+	defer func() { crawler = originalCrawler }()
+
+	if err := UpdateCommitCache(); err != nil {
+		t.Fatalf("UpdateCommitCache returned an error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"m1": {"main"},
+		"m2": {"main", "feat/x"},
+		"x1": {"feat/x"},
+		// A new branch is read until it reaches a cached commit
+		"y1": {"feat/y"},
+	}
+	if len(cache.commits) != len(expected) {
+		t.Errorf("Expected %d commits, got %d", len(expected), len(cache.commits))
+	}
+	for id, branches := range expected {
+		if got := cache.commits[id].Branches; !reflect.DeepEqual(got, branches) {
+			t.Errorf("Expected commit %s on %v, got %v", id, branches, got)
+		}
+	}
+
+	heads := map[string]string{"main": "m2", "feat/x": "m2", "feat/y": "y1"}
+	if state := cache.RepoStates()[1]; !reflect.DeepEqual(state.Branches, heads) || state.LastSeenSHA != "m2" {
+		t.Errorf("Expected branch heads %v, got %+v", heads, state)
+	}
+}
+
+func TestSyncPicksUpNewBranchPatterns(t *testing.T) {
+	owner := &github.User{Login: github.String("testuser")}
+	date := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	commit := func(sha string) *github.RepositoryCommit {
+		return &github.RepositoryCommit{
+			SHA:     github.String(sha),
+			HTMLURL: github.String("https://github.com/testuser/gordon/commit/" + sha),
+			Commit: &github.Commit{
+				Message: github.String("commit " + sha),
+				Author:  &github.CommitAuthor{Date: &github.Timestamp{Time: date}},
+			},
+		}
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetUserRepos,
+			[]*github.Repository{
+				{ID: github.Int64(1), Name: github.String("gordon"), Owner: owner, DefaultBranch: github.String("main"), PushedAt: &github.Timestamp{Time: date}},
+			},
+			[]*github.Repository{
+				{ID: github.Int64(1), Name: github.String("gordon"), Owner: owner, DefaultBranch: github.String("main"), PushedAt: &github.Timestamp{Time: date}},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposBranchesByOwnerByRepo,
+			[]*github.Branch{{Name: github.String("main")}, {Name: github.String("feat/x")}},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("sha") == "feat/x" {
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{commit("x1"), commit("m1")})
+					return
+				}
+				json.NewEncoder(w).Encode([]*github.RepositoryCommit{commit("m1")})
+			}),
+		),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(mockedHTTPClient)
+	defer func() { githubClient = originalClient }()
+
+	originalCache := cache
+	defer func() { cache = originalCache }()
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	cache.Update([]models.Commit{{ID: "m1", RepoName: "gordon", Timestamp: "2024-08-01T10:00:00Z", URL: "https://github.com/testuser/gordon/commit/m1", Branches: []string{"main"}}})
+	// The repository was synced before any branch pattern was configured
+	cache.SaveRepoStates([]models.RepoSyncState{{
+		ID: 1, Owner: "testuser", Name: "gordon", DefaultBranch: "main",
+		LastSeenSHA: "m1",
+		PushedAt:    date.Format(time.RFC3339),
+		Branches:    map[string]string{"main": "m1"},
+	}})
+
+	originalCrawler := crawler
+	crawler = NewCrawler(1, time.Minute)
+	crawler.branches = []string{"feat/*"}
+	defer func() { crawler = originalCrawler }()
+
+	if err := UpdateCommitCache(); err != nil {
+		t.Fatalf("UpdateCommitCache returned an error: %v", err)
+	}
+	if branches := cache.commits["x1"].Branches; !reflect.DeepEqual(branches, []string{"feat/x"}) {
+		t.Errorf("Expected the commits of the newly matching branch, got %v", branches)
+	}
+	if state := cache.RepoStates()[1]; !reflect.DeepEqual(state.BranchPatterns, []string{"feat/*"}) {
+		t.Errorf("Expected the branch patterns to be saved, got %+v", state)
+	}
+
+	// Once synced with the patterns, an unchanged repository is skipped again
+	if err := UpdateCommitCache(); err != nil {
+		t.Fatalf("UpdateCommitCache returned an error: %v", err)
+	}
+	if status := GetCrawlStatus(); status.ReposUnchanged != 1 {
+		t.Errorf("Expected the repository to be skipped, got %+v", status)
+	}
+}
+
+func TestSyncAttributesCommitsOfEveryAccount(t *testing.T) {
+	date := &github.Timestamp{Time: time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)}
+	repo := func(id int64, owner, name string) *github.Repository {
+		return &github.Repository{ID: github.Int64(id), Name: github.String(name), Owner: &github.User{Login: github.String(owner)}, DefaultBranch: github.String("main"), PushedAt: date}
+	}
+	commit := func(sha, login, email string) *github.RepositoryCommit {
+		c := &github.RepositoryCommit{
+			SHA:     github.String(sha),
+			HTMLURL: github.String("https://github.com/acme/repo/commit/" + sha),
+			Commit: &github.Commit{
+				Message: github.String("commit " + sha),
+				Author:  &github.CommitAuthor{Date: date, Email: github.String(email)},
+			},
+		}
+		if login != "" {
+			c.Author = &github.User{Login: github.String(login)}
+		}
+		return c
+	}
+	listCommits := func(commits map[string][]*github.RepositoryCommit) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			name := strings.Split(r.URL.Path, "/")[3]
+			if _, ok := commits[name]; !ok {
+				t.Errorf("Expected %s to be synced by another account", name)
+			}
+			json.NewEncoder(w).Encode(commits[name])
+		}
+	}
+
+	personalClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(mock.GetUser, github.User{Login: github.String("bnema")}),
+		mock.WithRequestMatchHandler(
+			mock.GetUserRepos,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if affiliation := r.URL.Query().Get("affiliation"); affiliation != "owner,organization_member" {
+					t.Errorf("Expected the configured affiliation, got %q", affiliation)
+				}
+				json.NewEncoder(w).Encode([]*github.Repository{repo(1, "bnema", "dotfiles"), repo(10, "acme", "api")})
+			}),
+		),
+		mock.WithRequestMatchHandler(mock.GetReposCommitsByOwnerByRepo, listCommits(map[string][]*github.RepositoryCommit{
+			"dotfiles": {commit("d1", "bnema", "")},
+			"api":      {commit("a1", "BNEMA", ""), commit("a2", "", "me@work.com"), commit("a3", "someone", "someone@acme.com")},
+		})),
+	)
+	workClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(mock.GetUser, github.User{Login: github.String("bnema-acme")}),
+		mock.WithRequestMatch(mock.GetUserRepos, []*github.Repository{repo(10, "acme", "api"), repo(11, "acme", "web")}),
+		mock.WithRequestMatchHandler(mock.GetReposCommitsByOwnerByRepo, listCommits(map[string][]*github.RepositoryCommit{
+			"web": {commit("w1", "bnema-acme", "noreply@github.com"), commit("w2", "someone", "someone@acme.com")},
+		})),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(personalClient)
+	defer func() { githubClient = originalClient }()
+
+	originalAccounts := githubAccounts
+	githubAccounts = []*githubAccount{
+		{name: "personal", affiliation: "owner,organization_member"},
+		{name: "work", client: github.NewClient(workClient), emails: []string{"me@work.com"}, affiliation: "organization_member"},
+	}
+	defer func() { githubAccounts = originalAccounts }()
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	originalCrawler := crawler
+	crawler = NewCrawler(2, time.Minute)
+	defer func() { crawler = originalCrawler }()
+
+	if err := UpdateCommitCache(); err != nil {
+		t.Fatalf("UpdateCommitCache returned an error: %v", err)
+	}
+
+	expected := map[string]string{"d1": "personal", "a1": "personal", "a2": "work", "w1": "work"}
+	if len(cache.commits) != len(expected) {
+		t.Errorf("Expected only the commits of the accounts, got %+v", cache.commits)
+	}
+	for id, account := range expected {
+		if got := cache.commits[id].Account; got != account {
+			t.Errorf("Expected commit %s to be attributed to %s, got %q", id, account, got)
+		}
+	}
+	if status := GetCrawlStatus(); status.ReposTotal != 3 || status.ReposFailed != 0 {
+		t.Errorf("Expected shared repositories to be synced once, got %+v", status)
+	}
+}
+
+func TestSyncDefersReposOfAccountsLowOnBudget(t *testing.T) {
+	listing := func(owner string, id int64) *github.Client {
+		return github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(
+				mock.GetUserRepos,
+				[]*github.Repository{{ID: github.Int64(id), Name: github.String(owner + "-repo"), Owner: &github.User{Login: github.String(owner)}, DefaultBranch: github.String("main")}},
+			),
+			mock.WithRequestMatchHandler(
+				mock.GetReposCommitsByOwnerByRepo,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if owner == "acme" {
+						t.Errorf("Expected no request for the account low on budget, got %s", r.URL.Path)
+					}
+					json.NewEncoder(w).Encode([]*github.RepositoryCommit{})
+				}),
+			),
+		))
+	}
+
+	low := NewRateLimiter()
+	header := http.Header{}
+	header.Set("X-RateLimit-Limit", "5000")
+	header.Set("X-RateLimit-Remaining", "20")
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	low.observe(RateResourceCore, header)
+
+	accounts := []*githubAccount{
+		{client: listing("bnema", 1), limiter: NewRateLimiter(), affiliation: "owner"},
+		{name: "work", client: listing("acme", 2), limiter: low, affiliation: "owner"},
+	}
+
+	originalCache, originalCrawler := cache, crawler
+	defer func() { cache, crawler = originalCache, originalCrawler }()
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	crawler = NewCrawler(1, time.Minute)
+
+	if err := crawler.Sync(context.Background(), accounts, cache.Merge); err != nil {
+		t.Fatalf("Sync returned an error: %v", err)
+	}
+	status := crawler.Status()
+	if status.ReposDeferred != 1 || status.ReposDone != 1 {
+		t.Errorf("Expected the repository of the work account to be deferred, got %+v", status)
+	}
+	if _, ok := cache.repos[2]; ok {
+		t.Error("Expected the deferred repository to stay unknown until it is synced")
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by this server
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	cursorNext = "n"
+	cursorPrev = "p"
+)
+
+// CommitPage is a page of the commit feed delimited by opaque cursors
+type CommitPage struct {
+	Commits    []models.Commit
+	TotalCount int
+	// NextCursor points to older commits, empty on the last page
+	NextCursor string
+	// PrevCursor points to newer commits, empty on the first page
+	PrevCursor string
+}
+
+// commitCursor is a position in the feed: a direction and the commit the page starts after
+type commitCursor struct {
+	direction string
+	entry     *commitEntry
+}
+
+func encodeCommitCursor(direction string, entry *commitEntry) string {
+	raw := direction + "|" + entry.time.UTC().Format(time.RFC3339Nano) + "|" + entry.public.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCommitCursor(token string) (commitCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return commitCursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 || (parts[0] != cursorNext && parts[0] != cursorPrev) || parts[2] == "" {
+		return commitCursor{}, ErrInvalidCursor
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return commitCursor{}, ErrInvalidCursor
+	}
+
+	return commitCursor{
+		direction: parts[0],
+		entry:     &commitEntry{public: models.Commit{ID: parts[2]}, time: timestamp},
+	}, nil
+}
+
+// GetCommitsPageFromCache returns the page of commits designated by the cursor, or the
+// first page when the cursor is empty. Cursors stay valid while new commits are inserted.
+func GetCommitsPageFromCache(cursor string, limit int, filter CommitFilter) (CommitPage, error) {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	entries := cache.readIndex().matching(filter)
+
+	start, end := 0, limit
+	if cursor != "" {
+		position, err := decodeCommitCursor(cursor)
+		if err != nil {
+			return CommitPage{}, err
+		}
+
+		// Index of the first entry coming after the cursor position
+		after := sort.Search(len(entries), func(i int) bool {
+			return position.entry.before(entries[i])
+		})
+
+		if position.direction == cursorNext {
+			start, end = after, after+limit
+		} else {
+			// The cursor entry itself is excluded from previous pages
+			first := sort.Search(len(entries), func(i int) bool {
+				return !entries[i].before(position.entry)
+			})
+			start, end = first-limit, first
+		}
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	if end > len(entries) {
+		end = len(entries)
+	}
+	if start > end {
+		start = end
+	}
+
+	page := CommitPage{
+		Commits:    publicCommits(entries[start:end]),
+		TotalCount: len(entries),
+	}
+	if end < len(entries) && end > 0 {
+		page.NextCursor = encodeCommitCursor(cursorNext, entries[end-1])
+	}
+	if start > 0 && start < len(entries) {
+		page.PrevCursor = encodeCommitCursor(cursorPrev, entries[start])
+	}
+	return page, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+func TestGetCommitsPageFromCacheIsStableAcrossInserts(t *testing.T) {
+	base := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	commitAt := func(id string, minutes int) models.Commit {
+		return models.Commit{ID: id, RepoName: "gordon", Message: "commit " + id, Timestamp: base.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339)}
+	}
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	var commits []models.Commit
+	for i := 0; i < 5; i++ {
+		commits = append(commits, commitAt(fmt.Sprintf("c%d", i), i))
+	}
+	// Same timestamp as c2, ordered by ID
+	commits = append(commits, commitAt("c2b", 2))
+	cache.Update(commits)
+
+	first, err := GetCommitsPageFromCache("", 3, DefaultCommitFilter())
+	if err != nil {
+		t.Fatalf("GetCommitsPageFromCache returned an error: %v", err)
+	}
+	assertCommitIDs(t, "first page", first.Commits, "c4", "c3", "c2b")
+	if first.PrevCursor != "" || first.NextCursor == "" {
+		t.Fatalf("Expected only a next cursor on the first page, got %+v", first)
+	}
+
+	// New commits arriving between two requests must not shift the next page
+	cache.Update([]models.Commit{commitAt("c5", 5), commitAt("c6", 6)})
+
+	second, err := GetCommitsPageFromCache(first.NextCursor, 3, DefaultCommitFilter())
+	if err != nil {
+		t.Fatalf("GetCommitsPageFromCache returned an error: %v", err)
+	}
+	assertCommitIDs(t, "second page", second.Commits, "c2", "c1", "c0")
+	if second.NextCursor != "" {
+		t.Errorf("Expected no next cursor on the last page, got %q", second.NextCursor)
+	}
+
+	previous, err := GetCommitsPageFromCache(second.PrevCursor, 3, DefaultCommitFilter())
+	if err != nil {
+		t.Fatalf("GetCommitsPageFromCache returned an error: %v", err)
+	}
+	assertCommitIDs(t, "previous page", previous.Commits, "c4", "c3", "c2b")
+	if previous.PrevCursor == "" {
+		t.Error("Expected a previous cursor towards the newly inserted commits")
+	}
+
+	if _, err := GetCommitsPageFromCache("not-a-cursor", 3, DefaultCommitFilter()); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func assertCommitIDs(t *testing.T, name string, commits []models.Commit, ids ...string) {
+	t.Helper()
+	if len(commits) != len(ids) {
+		t.Fatalf("[%s] Expected %d commits, got %d", name, len(ids), len(commits))
+	}
+	for i, id := range ids {
+		if commits[i].ID != id {
+			t.Errorf("[%s] Expected commit %d to be %s, got %s", name, i, id, commits[i].ID)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v63/github"
+)
+
+const (
+	// enrichmentBatchSize caps the commits enriched per cycle, the backlog is spread over several cycles
+	enrichmentBatchSize = 200
+	// enrichmentRateReserve is the part of the hourly API budget left to commit fetching
+	enrichmentRateReserve = 500
+)
+
+// enrichmentTimeout bounds an enrichment cycle, InitEnrichment applies the configured one
+var enrichmentTimeout = config.Defaults().EnrichmentTimeout
+
+// ErrRateBudgetExhausted stops enrichment before it eats the budget needed to fetch commits
+var ErrRateBudgetExhausted = errors.New("GitHub rate limit budget exhausted")
+
+// Enricher fetches the size of commits and the primary language of their repository
+type Enricher struct {
+	client  *github.Client
+	workers int
+
+	mutex     sync.Mutex
+	languages map[string]string
+	// failed holds commits the API cannot describe, such as commits of deleted repositories
+	failed map[string]bool
+}
+
+var enricher *Enricher
+
+func NewEnricher(client *github.Client, workers int) *Enricher {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Enricher{
+		client:    client,
+		workers:   workers,
+		languages: make(map[string]string),
+		failed:    make(map[string]bool),
+	}
+}
+
+// InitEnrichment enables the enrichment pipeline when configured
+func InitEnrichment(cfg *config.Config) {
+	enrichmentTimeout = cfg.EnrichmentTimeout
+	if !cfg.EnrichCommits {
+		return
+	}
+	client := GetGitHubClient()
+	if client == nil {
+		log.Warn("GitHub client is not initialized, commit enrichment is disabled")
+		return
+	}
+	enricher = NewEnricher(client, cfg.EnrichmentWorkers)
+	log.Info("Commit enrichment enabled", "workers", cfg.EnrichmentWorkers)
+}
+
+// EnrichCommitCache enriches the newest cached commits that were not enriched yet
+func EnrichCommitCache(ctx context.Context) error {
+	if enricher == nil {
+		return nil
+	}
+
+	pending := enricher.pending(enrichmentBatchSize)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	enriched, err := enricher.Enrich(ctx, pending)
+	if len(enriched) > 0 {
+		cache.Merge(enriched)
+		log.Info("Commits enriched", "commits", len(enriched), "pending", len(pending)-len(enriched))
+	}
+	return err
+}
+
+// pending returns up to limit cached commits without changes, newest first
+func (e *Enricher) pending(limit int) []models.Commit {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	var commits []models.Commit
+	for _, entry := range cache.readIndex().ordered {
+		if len(commits) == limit {
+			break
+		}
+		// Only commits of github.com can be described by the API
+		if _, _, ok := parseCommitURL(entry.commit.URL); !ok {
+			continue
+		}
+		if entry.commit.Changes == nil && !e.failed[entry.commit.ID] {
+			commits = append(commits, entry.commit)
+		}
+	}
+	return commits
+}
+
+// Enrich fetches changes and language of the commits with a bounded pool of workers.
+// It stops early when GitHub rate limits the requests or the budget runs low, returning
+// the commits enriched so far.
+func (e *Enricher) Enrich(ctx context.Context, commits []models.Commit) ([]models.Commit, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stopErr error
+	var stopOnce sync.Once
+	stop := func(err error) {
+		stopOnce.Do(func() {
+			stopErr = err
+			cancel()
+		})
+	}
+
+	jobs := make(chan models.Commit)
+	results := make(chan models.Commit)
+
+	var wg sync.WaitGroup
+	for i := 0; i < e.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for commit := range jobs {
+				client, limiter := e.clientFor(commit)
+				enriched, resp, err := e.enrichCommit(ctx, client, commit)
+				if err != nil {
+					switch {
+					case isRateLimitError(err):
+						stop(err)
+					case ctx.Err() == nil:
+						log.Warn("Failed to enrich commit", "repo", commit.RepoName, "error", err)
+						e.markFailed(commit.ID)
+					}
+					continue
+				}
+				results <- enriched
+				if remaining, known := e.remaining(limiter, resp); known && remaining < enrichmentRateReserve {
+					stop(fmt.Errorf("%w: %d requests left", ErrRateBudgetExhausted, remaining))
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, commit := range commits {
+			select {
+			case jobs <- commit:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var enriched []models.Commit
+	for commit := range results {
+		enriched = append(enriched, commit)
+	}
+	return enriched, stopErr
+}
+
+// clientFor returns the client and rate limiter of the account a commit is attributed to,
+// whose token can read its repository when the GITHUB_TOKEN one cannot. Commits of the
+// GITHUB_TOKEN account, or not attributed, use the client of the enricher.
+func (e *Enricher) clientFor(commit models.Commit) (*github.Client, *RateLimiter) {
+	for _, account := range githubAccounts {
+		if account.client != nil && account.name == commit.Account {
+			return account.client, account.limiter
+		}
+	}
+	return e.client, githubRateLimiter
+}
+
+func (e *Enricher) enrichCommit(ctx context.Context, client *github.Client, commit models.Commit) (models.Commit, *github.Response, error) {
+	owner, repo, ok := parseCommitURL(commit.URL)
+	if !ok {
+		return commit, nil, fmt.Errorf("cannot find the repository of commit %s", commit.ID)
+	}
+
+	details, resp, err := client.Repositories.GetCommit(ctx, owner, repo, commit.ID, nil)
+	if err != nil {
+		return commit, resp, err
+	}
+	commit.Changes = &models.CommitChanges{
+		Additions:    details.GetStats().GetAdditions(),
+		Deletions:    details.GetStats().GetDeletions(),
+		FilesChanged: len(details.Files),
+	}
+
+	language, err := e.language(ctx, client, owner, repo)
+	if err != nil {
+		return commit, resp, err
+	}
+	commit.Language = language
+	return commit, resp, nil
+}
+
+// language returns the primary language of a repository, fetched once per repository
+func (e *Enricher) language(ctx context.Context, client *github.Client, owner, repo string) (string, error) {
+	key := owner + "/" + repo
+	e.mutex.Lock()
+	language, ok := e.languages[key]
+	e.mutex.Unlock()
+	if ok {
+		return language, nil
+	}
+
+	repository, _, err := client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	e.mutex.Lock()
+	e.languages[key] = repository.GetLanguage()
+	e.mutex.Unlock()
+	return repository.GetLanguage(), nil
+}
+
+// remaining returns the core budget left, as tracked by the rate limiter of the token or
+// else as reported by the last response
+func (e *Enricher) remaining(limiter *RateLimiter, resp *github.Response) (int, bool) {
+	if remaining, known := limiter.Remaining(RateResourceCore); known {
+		return remaining, true
+	}
+	if resp != nil && resp.Rate.Limit > 0 {
+		return resp.Rate.Remaining, true
+	}
+	return 0, false
+}
+
+func (e *Enricher) markFailed(id string) {
+	e.mutex.Lock()
+	e.failed[id] = true
+	e.mutex.Unlock()
+}
+
+// parseCommitURL extracts owner and repository from a commit URL such as
+// https://github.com/owner/repo/commit/sha
+func parseCommitURL(url string) (owner, repo string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(url, "https://github.com/"), "/")
+	if len(parts) < 4 || parts[2] != "commit" || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func isRateLimitError(err error) bool {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	return errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr)
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+
+	"github.com/google/go-github/v63/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestEnrichCommitCache(t *testing.T) {
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepoByRef,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sha := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+				if sha == "gone" {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"message": "Not Found"}`))
+					return
+				}
+				json.NewEncoder(w).Encode(github.RepositoryCommit{
+					SHA:   github.String(sha),
+					Stats: &github.CommitStats{Additions: github.Int(10), Deletions: github.Int(4), Total: github.Int(14)},
+					Files: []*github.CommitFile{{Filename: github.String("main.go")}, {Filename: github.String("go.mod")}},
+				})
+			}),
+		),
+		// Both workers may look the language up at the same time
+		mock.WithRequestMatchHandler(
+			mock.GetReposByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(github.Repository{Name: github.String("gordon"), Language: github.String("Go")})
+			}),
+		),
+	)
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	originalEnricher := enricher
+	enricher = NewEnricher(github.NewClient(mockedHTTPClient), 2)
+	defer func() { enricher = originalEnricher }()
+
+	cache.Update([]models.Commit{
+		{ID: "a", RepoName: "gordon", Message: "feat: a", Timestamp: "2024-08-01T12:00:00Z", URL: "https://github.com/testuser/gordon/commit/a"},
+		{ID: "b", RepoName: "gordon", Message: "feat: b", Timestamp: "2024-08-02T12:00:00Z", URL: "https://github.com/testuser/gordon/commit/b"},
+		{ID: "gone", RepoName: "deleted", Message: "feat: gone", Timestamp: "2024-08-03T12:00:00Z", URL: "https://github.com/testuser/deleted/commit/gone"},
+	})
+	lastUpdated := cache.GetLastUpdated()
+
+	if err := EnrichCommitCache(context.Background()); err != nil {
+		t.Fatalf("EnrichCommitCache returned an error: %v", err)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		commit := cache.commits[id]
+		expected := models.CommitChanges{Additions: 10, Deletions: 4, FilesChanged: 2}
+		if commit.Changes == nil || *commit.Changes != expected || commit.Language != "Go" {
+			t.Errorf("Expected commit %s to be enriched, got %+v", id, commit)
+		}
+	}
+	if cache.commits["gone"].Changes != nil {
+		t.Error("Expected the commit of a deleted repository to stay as is")
+	}
+	if !cache.GetLastUpdated().Equal(lastUpdated) {
+		t.Error("Expected enrichment to keep the last update time")
+	}
+	if pending := enricher.pending(enrichmentBatchSize); len(pending) != 0 {
+		t.Errorf("Expected failed commits not to be retried, got %d pending", len(pending))
+	}
+
+	// Fetching a commit again keeps its enrichment
+	cache.Update([]models.Commit{
+		{ID: "a", RepoName: "gordon", Message: "feat: a", Timestamp: "2024-08-01T12:00:00Z", URL: "https://github.com/testuser/gordon/commit/a"},
+	})
+	if cache.commits["a"].Changes == nil {
+		t.Error("Expected enrichment to survive an update")
+	}
+
+	languages := computeCommitStats(time.Now()).Languages
+	if len(languages) != 1 || languages[0] != (models.LanguageStats{Language: "Go", Commits: 2, Additions: 20, Deletions: 8, FilesChanged: 4}) {
+		t.Errorf("Unexpected language stats %+v", languages)
+	}
+}
+
+func TestEnrichStopsWhenRateLimited(t *testing.T) {
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepoByRef,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-RateLimit-Limit", "5000")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", "1722513600")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+			}),
+		),
+	)
+
+	e := NewEnricher(github.NewClient(mockedHTTPClient), 4)
+	var commits []models.Commit
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		commits = append(commits, models.Commit{ID: id, RepoName: "gordon", URL: "https://github.com/testuser/gordon/commit/" + id})
+	}
+
+	enriched, err := e.Enrich(context.Background(), commits)
+	var rateLimitErr *github.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+	if len(enriched) != 0 {
+		t.Errorf("Expected no enriched commits, got %d", len(enriched))
+	}
+	if len(e.failed) != 0 {
+		t.Errorf("Expected rate limited commits to be retried later, got %d failed", len(e.failed))
+	}
+}
+
+func TestEnrichUsesTheClientOfTheCommitAccount(t *testing.T) {
+	describe := func(additions int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(github.RepositoryCommit{Stats: &github.CommitStats{Additions: github.Int(additions)}})
+		})
+	}
+	client := func(additions int) *github.Client {
+		return github.NewClient(mock.NewMockedHTTPClient(
+			mock.WithRequestMatchHandler(mock.GetReposCommitsByOwnerByRepoByRef, describe(additions)),
+			mock.WithRequestMatch(mock.GetReposByOwnerByRepo, github.Repository{Language: github.String("Go")}),
+		))
+	}
+	primary, work := client(1), client(2)
+
+	originalAccounts := githubAccounts
+	githubAccounts = []*githubAccount{{name: "personal"}, {name: "work", client: work, limiter: NewRateLimiter()}}
+	defer func() { githubAccounts = originalAccounts }()
+
+	e := NewEnricher(primary, 1)
+	enriched, err := e.Enrich(context.Background(), []models.Commit{
+		{ID: "a", Account: "personal", URL: "https://github.com/bnema/gordon/commit/a"},
+		{ID: "b", Account: "work", URL: "https://github.com/acme/api/commit/b"},
+	})
+	if err != nil {
+		t.Fatalf("Enrich returned an error: %v", err)
+	}
+	additions := make(map[string]int)
+	for _, commit := range enriched {
+		additions[commit.ID] = commit.Changes.Additions
+	}
+	if additions["a"] != 1 || additions["b"] != 2 {
+		t.Errorf("Expected each commit to be described by the client of its account, got %v", additions)
+	}
+}
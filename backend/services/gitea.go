@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+const giteaPageSize = 50
+
+// GiteaProvider reads the repositories of an account through the Gitea REST API, which
+// Forgejo and Codeberg share
+type GiteaProvider struct {
+	forge       string
+	instanceURL string
+	api         forgeAPI
+	// login of the token owner, resolved on the first listing
+	login string
+}
+
+func NewGiteaProvider(forge, instanceURL, token string, timeout time.Duration) *GiteaProvider {
+	authValue := ""
+	if token != "" {
+		authValue = "token " + token
+	}
+	return &GiteaProvider{
+		forge:       forge,
+		instanceURL: instanceURL,
+		api:         newForgeAPI(instanceURL+"/api/v1", "Authorization", authValue, timeout),
+	}
+}
+
+type giteaRepo struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Private       bool   `json:"private"`
+	Empty         bool   `json:"empty"`
+	Archived      bool   `json:"archived"`
+	Fork          bool   `json:"fork"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+type giteaCommit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+	// Author is the forge user matching the commit email, nil when there is none
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+func (p *GiteaProvider) Name() string {
+	return p.forge + ":" + hostOf(p.instanceURL)
+}
+
+func (p *GiteaProvider) Forge() string {
+	return p.forge
+}
+
+// ListRepos lists the repositories owned by the authenticated user. The API also lists the
+// repositories of its organizations and those it collaborates on, which are left out.
+func (p *GiteaProvider) ListRepos(ctx context.Context) ([]ForgeRepo, error) {
+	if p.login == "" {
+		var user struct {
+			Login string `json:"login"`
+		}
+		if _, err := p.api.get(ctx, "/user", url.Values{}, &user); err != nil {
+			return nil, fmt.Errorf("failed to get authenticated user: %w", err)
+		}
+		p.login = user.Login
+	}
+
+	var repos []ForgeRepo
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(giteaPageSize))
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var list []giteaRepo
+		if _, err := p.api.get(ctx, "/user/repos", query, &list); err != nil {
+			return nil, err
+		}
+		for _, repo := range list {
+			if !strings.EqualFold(repo.Owner.Login, p.login) {
+				continue
+			}
+			repos = append(repos, ForgeRepo{
+				ID:            repo.Owner.Login + "/" + repo.Name,
+				Owner:         repo.Owner.Login,
+				Name:          repo.Name,
+				IsPrivate:     repo.Private,
+				DefaultBranch: repo.DefaultBranch,
+				Empty:         repo.Empty,
+				Archived:      repo.Archived,
+				Fork:          repo.Fork,
+			})
+		}
+		// The instance may cap the page size below the requested limit
+		if len(list) == 0 {
+			return repos, nil
+		}
+	}
+}
+
+func (p *GiteaProvider) ListCommitsSince(ctx context.Context, repo ForgeRepo, since time.Time) ([]models.Commit, error) {
+	var commits []models.Commit
+	err := p.listCommits(ctx, repo, since, func(commit giteaCommit) {
+		commits = append(commits, p.toCommit(repo, commit))
+	})
+	return commits, err
+}
+
+// ListCommitsByAuthor lists the commits of a repository and keeps those whose author login,
+// name or email matches, the API has no author filter
+func (p *GiteaProvider) ListCommitsByAuthor(ctx context.Context, repo ForgeRepo, author string, since time.Time) ([]models.Commit, error) {
+	var commits []models.Commit
+	err := p.listCommits(ctx, repo, since, func(commit giteaCommit) {
+		var login string
+		if commit.Author != nil {
+			login = commit.Author.Login
+		}
+		if matchAuthor(author, login, commit.Commit.Author.Name, commit.Commit.Author.Email) {
+			commits = append(commits, p.toCommit(repo, commit))
+		}
+	})
+	return commits, err
+}
+
+// listCommits pages through the commits of the default branch, newest first, visiting those
+// made after since. Instances older than Gitea 1.22 ignore the since parameter, the dates
+// are checked here as well. Author dates are not ordered in history, rebased or amended
+// commits keep theirs, so an older commit does not end the listing.
+func (p *GiteaProvider) listCommits(ctx context.Context, repo ForgeRepo, since time.Time, visit func(giteaCommit)) error {
+	query := url.Values{}
+	query.Set("sha", repo.DefaultBranch)
+	query.Set("limit", strconv.Itoa(giteaPageSize))
+	// Skip the expensive parts of the response
+	query.Set("stat", "false")
+	query.Set("verification", "false")
+	query.Set("files", "false")
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var list []giteaCommit
+		header, err := p.api.get(ctx, "/repos/"+repo.ID+"/commits", query, &list)
+		if isForgeStatus(err, http.StatusConflict) {
+			// Gitea answers 409 Conflict for repositories without any commit
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, commit := range list {
+			if !since.IsZero() && !commit.Commit.Author.Date.After(since) {
+				continue
+			}
+			visit(commit)
+		}
+		if len(list) == 0 || header.Get("X-HasMore") == "false" {
+			return nil
+		}
+	}
+}
+
+func (p *GiteaProvider) toCommit(repo ForgeRepo, commit giteaCommit) models.Commit {
+	return models.Commit{
+		ID:        commit.SHA,
+		RepoName:  repo.Name,
+		Message:   commit.Commit.Message,
+		Timestamp: commit.Commit.Author.Date.UTC().Format(time.RFC3339),
+		URL:       commit.HTMLURL,
+		IsPrivate: repo.IsPrivate,
+		Forge:     p.forge,
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGiteaProvider(t *testing.T) {
+	since := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	commit := func(sha, login, email, date string) map[string]interface{} {
+		c := map[string]interface{}{
+			"sha":      sha,
+			"html_url": "https://codeberg.org/bnema/gart/commit/" + sha,
+			"commit": map[string]interface{}{
+				"message": "commit " + sha,
+				"author":  map[string]string{"name": login, "email": email, "date": date},
+			},
+		}
+		if login != "" {
+			c["author"] = map[string]string{"login": login}
+		}
+		return c
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		page := r.URL.Query().Get("page")
+		switch r.URL.Path {
+		case "/api/v1/user":
+			json.NewEncoder(w).Encode(map[string]string{"login": "bnema"})
+		case "/api/v1/user/repos":
+			if page != "1" {
+				json.NewEncoder(w).Encode([]interface{}{})
+				return
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": 1, "name": "gart", "default_branch": "main", "owner": map[string]string{"login": "bnema"}},
+				{"id": 2, "name": "empty", "empty": true, "owner": map[string]string{"login": "bnema"}},
+				{"id": 3, "name": "broken", "private": true, "default_branch": "main", "owner": map[string]string{"login": "bnema"}},
+				// Organization repositories are listed too, but not owned by the account
+				{"id": 4, "name": "team-repo", "default_branch": "main", "owner": map[string]string{"login": "some-org"}},
+			})
+		case "/api/v1/repos/bnema/gart/commits":
+			if r.URL.Query().Get("sha") != "main" {
+				t.Errorf("Expected the default branch to be listed, got %s", r.URL.RawQuery)
+			}
+			if page == "1" {
+				w.Header().Set("X-HasMore", "true")
+				json.NewEncoder(w).Encode([]interface{}{
+					commit("c3", "bnema", "bnema@example.com", "2024-08-03T12:00:00Z"),
+					// A rebased commit keeps its old author date
+					commit("rebased", "bnema", "bnema@example.com", "2024-07-15T12:00:00Z"),
+					commit("c2", "", "other@example.com", "2024-08-02T12:00:00Z"),
+				})
+				return
+			}
+			// Older instances ignore since, the provider skips older commits on its own
+			w.Header().Set("X-HasMore", "false")
+			json.NewEncoder(w).Encode([]interface{}{
+				commit("c1", "", "BNEMA@example.com", "2024-08-01T12:00:00Z"),
+				commit("c0", "bnema", "bnema@example.com", "2024-07-01T12:00:00Z"),
+			})
+		case "/api/v1/repos/bnema/broken/commits":
+			w.WriteHeader(http.StatusConflict)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewGiteaProvider(ForgeCodeberg, server.URL, "secret", time.Minute)
+	repos, err := provider.ListRepos(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepos returned an error: %v", err)
+	}
+	if len(repos) != 3 || repos[0].ID != "bnema/gart" || !repos[1].Empty || !repos[2].IsPrivate {
+		t.Fatalf("Unexpected repos %+v", repos)
+	}
+
+	commits, err := provider.ListCommitsSince(context.Background(), repos[0], since)
+	if err != nil {
+		t.Fatalf("ListCommitsSince returned an error: %v", err)
+	}
+	if len(commits) != 3 || commits[0].ID != "c3" || commits[2].ID != "c1" {
+		t.Fatalf("Expected the commits after since, got %+v", commits)
+	}
+	if commits[0].Forge != ForgeCodeberg || commits[0].Timestamp != "2024-08-03T12:00:00Z" || commits[0].URL != "https://codeberg.org/bnema/gart/commit/c3" {
+		t.Errorf("Unexpected commit %+v", commits[0])
+	}
+
+	if commits, err := provider.ListCommitsSince(context.Background(), repos[2], since); err != nil || len(commits) != 0 {
+		t.Errorf("Expected no commits for a repository Gitea considers empty, got %+v, %v", commits, err)
+	}
+
+	commits, err = provider.ListCommitsByAuthor(context.Background(), repos[0], "bnema@example.com", since)
+	if err != nil {
+		t.Fatalf("ListCommitsByAuthor returned an error: %v", err)
+	}
+	if len(commits) != 2 || commits[0].ID != "c3" || commits[1].ID != "c1" {
+		t.Errorf("Expected the commits matching the author email, got %+v", commits)
+	}
+}
@@ -2,14 +2,9 @@ package services
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"math/rand"
-	"path/filepath"
-	"sort"
-	"strings"
-	"sync"
+	"net/http"
 	"time"
 
 	"portfolio-backend/config"
@@ -20,34 +15,104 @@ import (
 	"golang.org/x/oauth2"
 )
 
-var (
-	rng     *rand.Rand
-	rngOnce sync.Once
-)
-
 var githubClient *github.Client
 
-// InitGitHubClient initializes the GitHub client
+// githubAccount is a GitHub identity whose repositories are crawled
+type githubAccount struct {
+	// name attributes commits to the account, commits are not attributed when it is empty
+	name string
+	// client and limiter are nil for the GITHUB_TOKEN account, which uses the shared ones
+	client      *github.Client
+	limiter     *RateLimiter
+	emails      []string
+	affiliation string
+
+	// login is resolved on the first sync that needs it
+	login string
+}
+
+// githubAccounts are the crawled accounts, the GITHUB_TOKEN account first
+var githubAccounts = []*githubAccount{{affiliation: "owner"}}
+
+// versionRepo is the repository whose latest release is reported as the application version
+var versionRepo = struct{ owner, name string }{config.Defaults().VersionOwner, config.Defaults().VersionRepo}
+
+// InitGitHubClient initializes the GitHub client, leaving it unset when no token is configured
 func InitGitHubClient(cfg *config.Config) {
-	ctx := context.Background()
+	versionRepo.owner, versionRepo.name = cfg.VersionOwner, cfg.VersionRepo
+	if cfg.GitHubToken == "" {
+		log.Warn("GITHUB_TOKEN is not set, GitHub features are disabled")
+		return
+	}
+
+	// Every GitHub request shares the same rate limit budget tracking
+	githubClient = github.NewClient(newGitHubHTTPClient(cfg.GitHubToken, githubRateLimiter))
+
+	accounts := []*githubAccount{{
+		name:        cfg.GitHubAccountName,
+		emails:      cfg.GitHubAuthorEmails,
+		affiliation: cfg.GitHubAffiliation,
+	}}
+	for _, account := range cfg.GitHubAccounts {
+		// Each token has a budget of its own
+		limiter := NewRateLimiter()
+		accounts = append(accounts, &githubAccount{
+			name:        account.Name,
+			client:      github.NewClient(newGitHubHTTPClient(account.Token, limiter)),
+			limiter:     limiter,
+			emails:      account.Emails,
+			affiliation: account.Affiliation,
+		})
+	}
+	githubAccounts = accounts
+}
+
+func (a *githubAccount) rateLimiter() *RateLimiter {
+	if a.limiter == nil {
+		return githubRateLimiter
+	}
+	return a.limiter
+}
+
+// GetAccountRateLimits returns the budget of the token of every crawled account, the
+// GITHUB_TOKEN account first
+func GetAccountRateLimits() []models.AccountRateLimits {
+	limits := make([]models.AccountRateLimits, 0, len(githubAccounts))
+	for _, account := range githubAccounts {
+		name := account.name
+		if name == "" {
+			name = "GITHUB_TOKEN"
+		}
+		limits = append(limits, models.AccountRateLimits{Account: name, Budgets: account.rateLimiter().Budgets()})
+	}
+	return limits
+}
+
+func (a *githubAccount) githubClient() *github.Client {
+	if a.client == nil {
+		return GetGitHubClient()
+	}
+	return a.client
+}
+
+// newGitHubHTTPClient returns an HTTP client authenticating with the token, its requests
+// going through the rate limiter
+func newGitHubHTTPClient(token string, limiter *RateLimiter) *http.Client {
+	if token == "" {
+		return &http.Client{Transport: limiter.Transport(nil)}
+	}
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: cfg.GitHubToken},
+		&oauth2.Token{AccessToken: token},
 	)
-	tc := oauth2.NewClient(ctx, ts)
-	githubClient = github.NewClient(tc)
+	tc := oauth2.NewClient(context.Background(), ts)
+	tc.Transport = limiter.Transport(tc.Transport)
+	return tc
 }
 
 func GetGitHubClient() *github.Client {
 	return githubClient
 }
 
-func getRNG() *rand.Rand {
-	rngOnce.Do(func() {
-		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
-	})
-	return rng
-}
-
 // GetVersionFromTag returns the current version of the application fril the latest tag in the repository
 func GetVersionFromTag() string {
 	client := GetGitHubClient()
@@ -58,7 +123,7 @@ func GetVersionFromTag() string {
 	ctx := context.Background()
 
 	// Get the latest release
-	release, _, err := client.Repositories.GetLatestRelease(ctx, "bnema", "portfolio-monorepo")
+	release, _, err := client.Repositories.GetLatestRelease(ctx, versionRepo.owner, versionRepo.name)
 	if err != nil {
 		// return the error from the GitHub API
 		return fmt.Sprintf("error: %s", err)
@@ -73,59 +138,23 @@ func GetVersionFromTag() string {
 	return version
 }
 
-// FetchAllCommitsFromAllRepos fetches all commits from all repositories
-func FetchAllCommitsFromAllRepos() ([]models.Commit, error) {
-	client := GetGitHubClient()
-	if client == nil {
-		return nil, errors.New("GitHub client is not initialized")
-	}
-	ctx := context.Background()
-
-	// List all repositories for the authenticated user
-	var allRepos []*github.Repository
-	opts := &github.RepositoryListByAuthenticatedUserOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
-		Type:        "owner", // Include both public and private repositories
+// FetchAllCommitsFromAllRepos fetches the new commits of every repository changed since
+// its last sync, handing the commits of each repository to publish as soon as it is done
+func FetchAllCommitsFromAllRepos(ctx context.Context, publish func([]models.Commit)) error {
+	if GetGitHubClient() == nil {
+		return errors.New("GitHub client is not initialized")
 	}
-	for {
-		repos, resp, err := client.Repositories.ListByAuthenticatedUser(ctx, opts)
-		if err != nil {
-			return nil, err
-		}
-		allRepos = append(allRepos, repos...)
-		if resp.NextPage == 0 {
-			break
-		}
-		opts.Page = resp.NextPage
-	}
-
-	// Now fetch commits from all repositories
-	var allCommits []models.Commit
-	for _, repo := range allRepos {
-		commits, err := fetchCommitsFromRepo(ctx, client, repo.GetOwner().GetLogin(), repo.GetName(), repo.GetPrivate())
-		if err != nil {
-			log.Warn(fmt.Sprintf("failed to fetch commits from repository: %s", err))
-			continue
-		}
-		allCommits = append(allCommits, commits...)
-	}
-
-	obfuscatedCommits := ObfuscatePrivateCommits(allCommits)
-
-	// Sort all commits by date (newest first)
-	sort.Slice(obfuscatedCommits, func(i, j int) bool {
-		timeI, _ := time.Parse(time.RFC3339, obfuscatedCommits[i].Timestamp)
-		timeJ, _ := time.Parse(time.RFC3339, obfuscatedCommits[j].Timestamp)
-		return timeI.After(timeJ)
-	})
-
-	return obfuscatedCommits, nil
+	return crawler.Sync(ctx, githubAccounts, publish)
 }
 
-// fetchCommitsFromRepo fetches all commits from a given repository
-func fetchCommitsFromRepo(ctx context.Context, client *github.Client, owner, repo string, isPrivate bool) ([]models.Commit, error) {
-	var commits []models.Commit
+// listRepoCommits fetches the commits of a repository branch made since the given time,
+// newest first. Paging ends at the first commit stop reports as already known. An empty
+// branch means the default branch and a zero since means every commit.
+func listRepoCommits(ctx context.Context, client *github.Client, owner, repo, branch string, since time.Time, stop func(sha string) bool) ([]*github.RepositoryCommit, error) {
+	var commits []*github.RepositoryCommit
 	opts := &github.CommitsListOptions{
+		SHA:         branch,
+		Since:       since,
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
 	for {
@@ -134,15 +163,10 @@ func fetchCommitsFromRepo(ctx context.Context, client *github.Client, owner, rep
 			return nil, err
 		}
 		for _, commit := range commitList {
-			newCommit := models.Commit{
-				ID:        commit.GetSHA(),
-				RepoName:  repo,
-				Message:   commit.GetCommit().GetMessage(),
-				Timestamp: commit.GetCommit().GetAuthor().GetDate().Format(time.RFC3339),
-				URL:       commit.GetHTMLURL(),
-				IsPrivate: isPrivate,
+			if stop != nil && stop(commit.GetSHA()) {
+				return commits, nil
 			}
-			commits = append(commits, newCommit)
+			commits = append(commits, commit)
 		}
 		if resp.NextPage == 0 {
 			break
@@ -152,162 +176,103 @@ func fetchCommitsFromRepo(ctx context.Context, client *github.Client, owner, rep
 	return commits, nil
 }
 
-// FetchRecentCommits fetches all commits authored by the authenticated user since the last update
-func FetchRecentCommits(lastUpdated time.Time) ([]models.Commit, error) {
-	client := GetGitHubClient()
-	if client == nil {
-		return nil, fmt.Errorf("GitHub client is not initialized")
+func newGitHubCommit(commit *github.RepositoryCommit, repo, branch string, isPrivate bool) models.Commit {
+	newCommit := models.Commit{
+		ID:        commit.GetSHA(),
+		RepoName:  repo,
+		Message:   commit.GetCommit().GetMessage(),
+		Timestamp: commit.GetCommit().GetAuthor().GetDate().Format(time.RFC3339),
+		URL:       commit.GetHTMLURL(),
+		IsPrivate: isPrivate,
+		Forge:     ForgeGitHub,
 	}
-
-	// Increase timeout to 2 minutes for larger repositories
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	user, _, err := client.Users.Get(ctx, "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get authenticated user: %w", err)
-	}
-	username := user.GetLogin()
-
-	query := fmt.Sprintf("author:%s", username)
-	opts := &github.SearchOptions{
-		Sort:  "author-date",
-		Order: "desc",
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
+	if branch != "" {
+		newCommit.Branches = []string{branch}
 	}
+	return newCommit
+}
 
-	var allCommits []models.Commit
-
-	// Convert lastUpdated to UTC for consistent comparison
-	lastUpdatedUTC := lastUpdated.UTC()
+// GitHubProvider is the CommitProvider of a GitHub account of FORGE_ACCOUNTS, such as a
+// GitHub Enterprise Server account or one restricted to an author. It is an adapter over
+// the API helpers of the crawler, which keeps syncing the GITHUB_TOKEN and GITHUB_ACCOUNTS
+// accounts branch by branch.
+type GitHubProvider struct {
+	account *githubAccount
+	host    string
+}
 
-	// Implement exponential backoff
-	backoff := time.Second
-	for i := 0; i < 3; i++ { // Try up to 3 times
-		result, resp, err := client.Search.Commits(ctx, query, opts)
+// NewGitHubProvider creates a provider for github.com or, given the URL of its API, a
+// GitHub Enterprise Server instance
+func NewGitHubProvider(apiURL, token string) (*GitHubProvider, error) {
+	// Each token has a budget of its own
+	client := github.NewClient(newGitHubHTTPClient(token, NewRateLimiter()))
+	if apiURL != "" && hostOf(apiURL) != "api.github.com" {
+		var err error
+		client, err = client.WithEnterpriseURLs(apiURL, apiURL)
 		if err != nil {
-			// Check if the error is due to rate limiting
-			if _, ok := err.(*github.RateLimitError); ok {
-				time.Sleep(backoff)
-				backoff *= 2
-				continue
-			}
-			return nil, fmt.Errorf("failed to search commits: %w", err)
+			return nil, fmt.Errorf("invalid GitHub URL %s: %w", apiURL, err)
 		}
-
-		for _, commit := range result.Commits {
-			commitDate := commit.GetCommit().GetAuthor().GetDate()
-			commitTimeStamp := commitDate.Time
-
-			if commitTimeStamp.Before(lastUpdatedUTC) || commitTimeStamp.Equal(lastUpdatedUTC) {
-				// We've reached commits older than or equal to the last update, so we're done
-				return allCommits, nil
-			}
-
-			newCommit := models.Commit{
-				ID:        commit.GetSHA(),
-				RepoName:  commit.GetRepository().GetName(),
-				Message:   commit.GetCommit().GetMessage(),
-				Timestamp: commitDate.Format(time.RFC3339),
-				URL:       commit.GetHTMLURL(),
-				IsPrivate: commit.GetRepository().GetPrivate(),
-			}
-			allCommits = append(allCommits, newCommit)
-		}
-
-		if resp.NextPage == 0 {
-			break
-		}
-		opts.Page = resp.NextPage
 	}
-
-	return ObfuscatePrivateCommits(allCommits), nil
+	return &GitHubProvider{
+		account: &githubAccount{client: client, affiliation: "owner"},
+		host:    hostOf(client.BaseURL.String()),
+	}, nil
 }
 
-// FetchProjectsContent fetches all project content from the GitHub repository
-func FetchProjectsContent() ([]models.Project, error) {
-	client := GetGitHubClient()
-	if client == nil {
-		return nil, errors.New("GitHub client is not initialized")
-	}
-	ctx := context.Background()
-
-	owner := "bnema"
-	repo := "portfolio-mono"
-	path := "content/projects"
+func (p *GitHubProvider) Name() string {
+	return ForgeGitHub + ":" + p.host
+}
 
-	var projects []models.Project
+func (p *GitHubProvider) Forge() string {
+	return ForgeGitHub
+}
 
-	opts := &github.RepositoryContentGetOptions{}
-	_, directoryContents, _, err := client.Repositories.GetContents(ctx, owner, repo, path, opts)
+func (p *GitHubProvider) ListRepos(ctx context.Context) ([]ForgeRepo, error) {
+	repos, err := listRepos(ctx, p.account.githubClient(), p.account.affiliation)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch directory contents: %w", err)
+		return nil, err
 	}
-
-	for _, file := range directoryContents {
-		if filepath.Ext(file.GetName()) != ".md" {
-			continue
-		}
-
-		fileContent, _, _, err := client.Repositories.GetContents(ctx, owner, repo, file.GetPath(), opts)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch file content for %s: %w", file.GetName(), err)
-		}
-
-		content, err := base64.StdEncoding.DecodeString(*fileContent.Content)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode content for %s: %w", file.GetName(), err)
-		}
-
-		contentStr := string(content)
-		projectOrigin := ""
-		lines := strings.Split(contentStr, "\n")
-		if len(lines) > 0 && strings.HasPrefix(lines[0], "[project_origin]:") {
-			projectOrigin = strings.TrimSpace(strings.TrimPrefix(lines[0], "[project_origin]:"))
-			contentStr = strings.Join(lines[1:], "\n")
-		}
-
-		title := strings.TrimSuffix(file.GetName(), filepath.Ext(file.GetName()))
-		slug := strings.ToLower(strings.ReplaceAll(title, " ", "-"))
-
-		projects = append(projects, models.Project{
-			Title:         title,
-			Slug:          slug,
-			ProjectOrigin: projectOrigin,
-			Content:       contentStr,
+	forgeRepos := make([]ForgeRepo, 0, len(repos))
+	for _, repo := range repos {
+		forgeRepos = append(forgeRepos, ForgeRepo{
+			ID:            repo.GetFullName(),
+			Owner:         repo.GetOwner().GetLogin(),
+			Name:          repo.GetName(),
+			IsPrivate:     repo.GetPrivate(),
+			DefaultBranch: repo.GetDefaultBranch(),
+			Archived:      repo.GetArchived(),
+			Fork:          repo.GetFork(),
 		})
 	}
+	return forgeRepos, nil
+}
 
-	return projects, nil
+func (p *GitHubProvider) ListCommitsSince(ctx context.Context, repo ForgeRepo, since time.Time) ([]models.Commit, error) {
+	return p.listCommits(ctx, repo, since, func(*github.RepositoryCommit) bool { return true })
 }
 
-// ObfuscatePrivateCommits replaces private commit data with obfuscated strings
-func ObfuscatePrivateCommits(commits []models.Commit) []models.Commit {
-	for i, commit := range commits {
-		if commit.IsPrivate {
-			commits[i].RepoName = obfuscateString(commit.RepoName)
-			commits[i].Message = obfuscateString(commit.Message)
-			commits[i].ID = obfuscateString(commit.ID)
-			commits[i].URL = "#"
-		}
-	}
-	return commits
+// ListCommitsByAuthor keeps the commits whose author login, name or email matches
+func (p *GitHubProvider) ListCommitsByAuthor(ctx context.Context, repo ForgeRepo, author string, since time.Time) ([]models.Commit, error) {
+	return p.listCommits(ctx, repo, since, func(commit *github.RepositoryCommit) bool {
+		gitAuthor := commit.GetCommit().GetAuthor()
+		return matchAuthor(author, commit.GetAuthor().GetLogin(), gitAuthor.GetName(), gitAuthor.GetEmail())
+	})
 }
 
-// obfuscateString replaces all characters in a string with obfuscated characters
-func obfuscateString(s string) string {
-	obfuscatedChars := []rune("░▒▓█▄▀■□▢▣▤▥▦▧▨▩▆▅█▉▇▊▄▋▌_▍▃▂▁")
-	r := getRNG()
-
-	var result strings.Builder
-	for _, char := range s {
-		if char == ' ' || char == '\n' {
-			result.WriteRune(char)
-		} else {
-			result.WriteRune(obfuscatedChars[r.Intn(len(obfuscatedChars))])
+func (p *GitHubProvider) listCommits(ctx context.Context, repo ForgeRepo, since time.Time, keep func(*github.RepositoryCommit) bool) ([]models.Commit, error) {
+	repoCommits, err := listRepoCommits(ctx, p.account.githubClient(), repo.Owner, repo.Name, repo.DefaultBranch, since, nil)
+	// GitHub does not tell empty repositories apart when listing them
+	if isEmptyRepoError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var commits []models.Commit
+	for _, commit := range repoCommits {
+		if keep(commit) {
+			commits = append(commits, newGitHubCommit(commit, repo.Name, repo.DefaultBranch, repo.IsPrivate))
 		}
 	}
-	return result.String()
+	return commits, nil
 }
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
 	"testing"
 	"time"
 
@@ -10,72 +14,109 @@ import (
 	"github.com/migueleliasweb/go-github-mock/src/mock"
 )
 
-func TestFetchRecentCommits(t *testing.T) {
-	// Set up test data
-	testTime := time.Now()
-	mockCommits := []*github.CommitResult{
-		{
-			SHA: github.String("abc123"),
-			Commit: &github.Commit{
-				Message: github.String("Test commit"),
-				Author: &github.CommitAuthor{
-					Date: &github.Timestamp{Time: testTime},
-				},
-			},
-			HTMLURL: github.String("https://github.com/bnema/repo/commit/abc123"),
-			Repository: &github.Repository{
-				Name:    github.String("test-repo"),
-				Private: github.Bool(false),
-			},
-		},
-	}
-
-	// Create mocked HTTP client
+// TestFetchAllCommitsFromAllRepos covers what TestFetchRecentCommits did before the crawler
+// replaced the author search: commits of the account are fetched and converted
+func TestFetchAllCommitsFromAllRepos(t *testing.T) {
+	testTime := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
 	mockedHTTPClient := mock.NewMockedHTTPClient(
 		mock.WithRequestMatch(
-			mock.GetUsersByUsername,
-			github.User{Login: github.String("testuser")},
+			mock.GetUserRepos,
+			[]*github.Repository{{ID: github.Int64(1), Name: github.String("test-repo"), Owner: &github.User{Login: github.String("bnema")}, DefaultBranch: github.String("main")}},
 		),
 		mock.WithRequestMatch(
-			mock.GetUser,
-			github.User{Login: github.String("testuser")},
-		),
-		mock.WithRequestMatch(
-			mock.GetSearchCommits,
-			&github.CommitsSearchResult{
-				Commits: mockCommits,
-			},
+			mock.GetReposCommitsByOwnerByRepo,
+			[]*github.RepositoryCommit{{
+				SHA:     github.String("abc123"),
+				HTMLURL: github.String("https://github.com/bnema/test-repo/commit/abc123"),
+				Commit:  &github.Commit{Message: github.String("Test commit"), Author: &github.CommitAuthor{Date: &github.Timestamp{Time: testTime}}},
+			}},
 		),
 	)
 
-	// Override the GitHub client
-	originalClient := githubClient
+	originalClient, originalCache, originalCrawler := githubClient, cache, crawler
+	defer func() { githubClient, cache, crawler = originalClient, originalCache, originalCrawler }()
 	githubClient = github.NewClient(mockedHTTPClient)
-	defer func() { githubClient = originalClient }()
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	crawler = NewCrawler(1, time.Minute)
 
-	// Run the test
-	since := time.Now().AddDate(0, -1, 0)
-	commits, err := FetchRecentCommits(since)
-
-	// Check results
+	var commits []models.Commit
+	err := FetchAllCommitsFromAllRepos(context.Background(), func(published []models.Commit) {
+		commits = append(commits, published...)
+	})
 	if err != nil {
-		t.Fatalf("FetchRecentCommits returned an error: %v", err)
-	}
-
-	if len(commits) != 1 {
-		t.Errorf("Expected 1 commit, got %d", len(commits))
+		t.Fatalf("FetchAllCommitsFromAllRepos returned an error: %v", err)
 	}
 
-	expectedCommit := models.Commit{
+	expected := []models.Commit{{
 		ID:        "abc123",
 		RepoName:  "test-repo",
 		Message:   "Test commit",
 		Timestamp: testTime.Format(time.RFC3339),
-		URL:       "https://github.com/bnema/repo/commit/abc123",
-		IsPrivate: false,
+		URL:       "https://github.com/bnema/test-repo/commit/abc123",
+		Forge:     ForgeGitHub,
+		Branches:  []string{"main"},
+	}}
+	if !reflect.DeepEqual(commits, expected) {
+		t.Errorf("Expected commits %+v, got %+v", expected, commits)
+	}
+}
+
+func TestGitHubProvider(t *testing.T) {
+	owner := &github.User{Login: github.String("bnema")}
+	date := time.Date(2024, 8, 2, 12, 0, 0, 0, time.UTC)
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetUserRepos,
+			[]*github.Repository{
+				{ID: github.Int64(1), Name: github.String("gordon"), FullName: github.String("bnema/gordon"), Owner: owner, DefaultBranch: github.String("main")},
+				{ID: github.Int64(2), Name: github.String("old"), FullName: github.String("bnema/old"), Owner: owner, Archived: github.Bool(true), Private: github.Bool(true)},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCommitsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("sha") != "main" {
+					t.Errorf("Expected the default branch to be listed, got %s", r.URL.RawQuery)
+				}
+				json.NewEncoder(w).Encode([]*github.RepositoryCommit{
+					{
+						SHA:     github.String("a1"),
+						HTMLURL: github.String("https://github.com/bnema/gordon/commit/a1"),
+						Author:  &github.User{Login: github.String("bnema")},
+						Commit:  &github.Commit{Message: github.String("feat: a"), Author: &github.CommitAuthor{Date: &github.Timestamp{Time: date}}},
+					},
+					{
+						SHA:     github.String("b1"),
+						HTMLURL: github.String("https://github.com/bnema/gordon/commit/b1"),
+						Commit:  &github.Commit{Message: github.String("fix: b"), Author: &github.CommitAuthor{Name: github.String("someone"), Date: &github.Timestamp{Time: date}}},
+					},
+				})
+			}),
+		),
+	)
+	provider := &GitHubProvider{account: &githubAccount{client: github.NewClient(mockedHTTPClient), affiliation: "owner"}, host: "api.github.com"}
+
+	repos, err := provider.ListRepos(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepos returned an error: %v", err)
+	}
+	if len(repos) != 2 || repos[0].ID != "bnema/gordon" || repos[0].DefaultBranch != "main" || !repos[1].Archived || !repos[1].IsPrivate {
+		t.Fatalf("Unexpected repos %+v", repos)
 	}
 
-	if commits[0] != expectedCommit {
-		t.Errorf("Expected commit %+v, got %+v", expectedCommit, commits[0])
+	commits, err := provider.ListCommitsSince(context.Background(), repos[0], date.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListCommitsSince returned an error: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Forge != ForgeGitHub || commits[0].RepoName != "gordon" || commits[0].Branches[0] != "main" {
+		t.Errorf("Unexpected commits %+v", commits)
+	}
+
+	commits, err = provider.ListCommitsByAuthor(context.Background(), repos[0], "someone", date.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListCommitsByAuthor returned an error: %v", err)
+	}
+	if len(commits) != 1 || commits[0].ID != "b1" {
+		t.Errorf("Expected the commits of the author, got %+v", commits)
 	}
 }
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+const gitlabPageSize = 100
+
+// GitLabProvider reads the projects owned by an account through the GitLab REST API
+type GitLabProvider struct {
+	instanceURL string
+	api         forgeAPI
+}
+
+func NewGitLabProvider(instanceURL, token string, timeout time.Duration) *GitLabProvider {
+	return &GitLabProvider{
+		instanceURL: instanceURL,
+		api:         newForgeAPI(instanceURL+"/api/v4", "PRIVATE-TOKEN", token, timeout),
+	}
+}
+
+type gitlabProject struct {
+	ID            int64  `json:"id"`
+	Path          string `json:"path"`
+	Visibility    string `json:"visibility"`
+	DefaultBranch string `json:"default_branch"`
+	Archived      bool   `json:"archived"`
+	// ForkedFromProject is only set for forks
+	ForkedFromProject *struct{} `json:"forked_from_project"`
+	Namespace         struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+}
+
+type gitlabCommit struct {
+	ID           string    `json:"id"`
+	Message      string    `json:"message"`
+	AuthoredDate time.Time `json:"authored_date"`
+	WebURL       string    `json:"web_url"`
+}
+
+func (p *GitLabProvider) Name() string {
+	return ForgeGitLab + ":" + hostOf(p.instanceURL)
+}
+
+func (p *GitLabProvider) Forge() string {
+	return ForgeGitLab
+}
+
+// ListRepos lists the projects owned by the authenticated user
+func (p *GitLabProvider) ListRepos(ctx context.Context) ([]ForgeRepo, error) {
+	var repos []ForgeRepo
+	query := url.Values{}
+	query.Set("owned", "true")
+	query.Set("order_by", "id")
+	query.Set("per_page", strconv.Itoa(gitlabPageSize))
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+		var projects []gitlabProject
+		header, err := p.api.get(ctx, "/projects", query, &projects)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			repos = append(repos, ForgeRepo{
+				ID:    strconv.FormatInt(project.ID, 10),
+				Owner: project.Namespace.FullPath,
+				Name:  project.Path,
+				// Internal projects are only visible to users of the instance
+				IsPrivate:     project.Visibility != "public",
+				DefaultBranch: project.DefaultBranch,
+				// Projects without any commit have no default branch
+				Empty:    project.DefaultBranch == "",
+				Archived: project.Archived,
+				Fork:     project.ForkedFromProject != nil,
+			})
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return repos, nil
+}
+
+func (p *GitLabProvider) ListCommitsSince(ctx context.Context, repo ForgeRepo, since time.Time) ([]models.Commit, error) {
+	return p.listCommits(ctx, repo, "", since)
+}
+
+// ListCommitsByAuthor lists the commits of a project, filtered by the API on the author
+// name or email
+func (p *GitLabProvider) ListCommitsByAuthor(ctx context.Context, repo ForgeRepo, author string, since time.Time) ([]models.Commit, error) {
+	return p.listCommits(ctx, repo, author, since)
+}
+
+func (p *GitLabProvider) listCommits(ctx context.Context, repo ForgeRepo, author string, since time.Time) ([]models.Commit, error) {
+	var commits []models.Commit
+	query := url.Values{}
+	query.Set("ref_name", repo.DefaultBranch)
+	query.Set("per_page", strconv.Itoa(gitlabPageSize))
+	if !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	if author != "" {
+		query.Set("author", author)
+	}
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+		var list []gitlabCommit
+		header, err := p.api.get(ctx, "/projects/"+repo.ID+"/repository/commits", query, &list)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range list {
+			commits = append(commits, models.Commit{
+				ID:        commit.ID,
+				RepoName:  repo.Name,
+				Message:   commit.Message,
+				Timestamp: commit.AuthoredDate.UTC().Format(time.RFC3339),
+				URL:       commit.WebURL,
+				IsPrivate: repo.IsPrivate,
+				Forge:     ForgeGitLab,
+			})
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return commits, nil
+}
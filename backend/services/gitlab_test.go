@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+func TestGitLabProvider(t *testing.T) {
+	since := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/api/v4/projects":
+			if query.Get("owned") != "true" {
+				t.Errorf("Expected owned projects only, got %s", r.URL.RawQuery)
+			}
+			if query.Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"id": 1, "path": "gordon", "visibility": "public", "default_branch": "main", "namespace": map[string]string{"full_path": "bnema"}},
+				})
+				return
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": 2, "path": "secret", "visibility": "internal", "default_branch": "main", "namespace": map[string]string{"full_path": "bnema/work"}},
+				{"id": 3, "path": "empty", "visibility": "public", "namespace": map[string]string{"full_path": "bnema"}},
+			})
+		case "/api/v4/projects/1/repository/commits":
+			if query.Get("ref_name") != "main" || query.Get("since") != "2024-08-01T00:00:00Z" {
+				t.Errorf("Unexpected commit query %s", r.URL.RawQuery)
+			}
+			commits := []map[string]string{
+				{"id": "a1", "message": "feat: gitlab", "authored_date": "2024-08-02T14:00:00.000+02:00", "web_url": "https://gitlab.example/bnema/gordon/-/commit/a1", "author_name": "bnema"},
+			}
+			if query.Get("author") == "someone" {
+				commits = nil
+			}
+			json.NewEncoder(w).Encode(commits)
+		case "/api/v4/projects/2/repository/commits":
+			json.NewEncoder(w).Encode([]map[string]string{
+				{"id": "b1", "message": "fix: secret", "authored_date": "2024-08-03T12:00:00Z", "web_url": "https://gitlab.example/bnema/work/secret/-/commit/b1"},
+			})
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewGitLabProvider(server.URL, "secret", time.Minute)
+	repos, err := provider.ListRepos(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepos returned an error: %v", err)
+	}
+	expectedRepos := []ForgeRepo{
+		{ID: "1", Owner: "bnema", Name: "gordon", DefaultBranch: "main"},
+		{ID: "2", Owner: "bnema/work", Name: "secret", IsPrivate: true, DefaultBranch: "main"},
+		{ID: "3", Owner: "bnema", Name: "empty", Empty: true},
+	}
+	if !reflect.DeepEqual(repos, expectedRepos) {
+		t.Fatalf("Expected repos %+v, got %+v", expectedRepos, repos)
+	}
+
+	commits, err := provider.ListCommitsSince(context.Background(), repos[0], since)
+	if err != nil {
+		t.Fatalf("ListCommitsSince returned an error: %v", err)
+	}
+	expected := []models.Commit{{
+		ID:        "a1",
+		RepoName:  "gordon",
+		Message:   "feat: gitlab",
+		Timestamp: "2024-08-02T12:00:00Z",
+		URL:       "https://gitlab.example/bnema/gordon/-/commit/a1",
+		Forge:     ForgeGitLab,
+	}}
+	if !reflect.DeepEqual(commits, expected) {
+		t.Errorf("Expected commits %+v, got %+v", expected, commits)
+	}
+
+	commits, err = provider.ListCommitsByAuthor(context.Background(), repos[0], "someone", since)
+	if err != nil {
+		t.Fatalf("ListCommitsByAuthor returned an error: %v", err)
+	}
+	if len(commits) != 0 {
+		t.Errorf("Expected the author filter to be applied by the API, got %+v", commits)
+	}
+}
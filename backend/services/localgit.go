@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ForgeLocal tags commits read from repositories on disk
+const ForgeLocal = "local"
+
+// LocalGitProvider reads the history of git repositories found under local directories,
+// with go-git so no git binary is needed
+type LocalGitProvider struct {
+	dirs []string
+	// emails restricts commits to these author emails, every commit is kept when empty
+	emails    []string
+	isPrivate bool
+}
+
+// NewLocalGitProvider creates a provider scanning the given directories
+func NewLocalGitProvider(dirs, emails []string, isPrivate bool) (*LocalGitProvider, error) {
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan %s: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("cannot scan %s: not a directory", dir)
+		}
+	}
+	return &LocalGitProvider{dirs: dirs, emails: emails, isPrivate: isPrivate}, nil
+}
+
+func (p *LocalGitProvider) Name() string {
+	return ForgeLocal
+}
+
+func (p *LocalGitProvider) Forge() string {
+	return ForgeLocal
+}
+
+// ListRepos walks the directories for working trees and bare repositories. Hidden
+// directories are skipped and repositories are not searched for nested ones.
+func (p *LocalGitProvider) ListRepos(ctx context.Context) ([]ForgeRepo, error) {
+	var repos []ForgeRepo
+	for _, dir := range p.dirs {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories are not worth failing the whole scan
+				log.Warn("Failed to scan directory", "path", path, "error", err)
+				return fs.SkipDir
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if !entry.IsDir() {
+				return nil
+			}
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return fs.SkipDir
+			}
+			if !isGitRepository(path) {
+				return nil
+			}
+
+			repo, err := p.repo(dir, path)
+			if err != nil {
+				log.Warn("Failed to open git repository", "path", path, "error", err)
+			} else {
+				repos = append(repos, repo)
+			}
+			return fs.SkipDir
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return repos, nil
+}
+
+// isGitRepository reports whether a directory is a working tree, including linked
+// worktrees whose .git is a file, or a bare repository
+func isGitRepository(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, git.GitDirName)); err == nil {
+		return true
+	}
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// repo opens the repository at path, named after its path relative to the scanned dir so
+// that repositories sharing a directory name in different places stay apart
+func (p *LocalGitProvider) repo(dir, path string) (ForgeRepo, error) {
+	repository, err := openLocalRepository(path)
+	if err != nil {
+		return ForgeRepo{}, err
+	}
+
+	name, err := filepath.Rel(dir, path)
+	if err != nil || name == "." {
+		name = filepath.Base(path)
+	}
+	repo := ForgeRepo{
+		ID:        path,
+		Name:      strings.TrimSuffix(filepath.ToSlash(name), ".git"),
+		IsPrivate: p.isPrivate,
+	}
+	head, err := repository.Head()
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		repo.Empty = true
+	case err != nil:
+		return ForgeRepo{}, err
+	case head.Name().IsBranch():
+		repo.DefaultBranch = head.Name().Short()
+	}
+	return repo, nil
+}
+
+func openLocalRepository(path string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+// ListCommitsSince reads the history of the checked out branch, keeping the commits of the
+// configured author emails
+func (p *LocalGitProvider) ListCommitsSince(ctx context.Context, repo ForgeRepo, since time.Time) ([]models.Commit, error) {
+	return p.log(ctx, repo, since, func(commit *object.Commit) bool {
+		if len(p.emails) == 0 {
+			return true
+		}
+		return matchAuthor(commit.Author.Email, p.emails...)
+	})
+}
+
+// ListCommitsByAuthor reads the history of the checked out branch, keeping the commits
+// whose author name or email matches
+func (p *LocalGitProvider) ListCommitsByAuthor(ctx context.Context, repo ForgeRepo, author string, since time.Time) ([]models.Commit, error) {
+	return p.log(ctx, repo, since, func(commit *object.Commit) bool {
+		return matchAuthor(author, commit.Author.Name, commit.Author.Email)
+	})
+}
+
+// log walks the history from HEAD, newest first, and converts the commits keep accepts
+func (p *LocalGitProvider) log(ctx context.Context, repo ForgeRepo, since time.Time, keep func(*object.Commit) bool) ([]models.Commit, error) {
+	repository, err := openLocalRepository(repo.ID)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repository.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime}
+	if !since.IsZero() {
+		opts.Since = &since
+	}
+	iter, err := repository.Log(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var commits []models.Commit
+	err = iter.ForEach(func(commit *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !keep(commit) {
+			return nil
+		}
+		newCommit := models.Commit{
+			ID:        commit.Hash.String(),
+			RepoName:  repo.Name,
+			Message:   strings.TrimRight(commit.Message, "\n"),
+			Timestamp: commit.Author.When.UTC().Format(time.RFC3339),
+			IsPrivate: repo.IsPrivate,
+			Forge:     ForgeLocal,
+		}
+		if repo.DefaultBranch != "" {
+			newCommit.Branches = []string{repo.DefaultBranch}
+		}
+		commits = append(commits, newCommit)
+		return nil
+	})
+	return commits, err
+}
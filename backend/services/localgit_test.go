@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestLocalGitProvider(t *testing.T) {
+	root := t.TempDir()
+	date := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	repository, err := git.PlainInit(filepath.Join(root, "projects", "gart"), false)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatalf("Failed to open worktree: %v", err)
+	}
+	commit := func(message, email string, when time.Time) string {
+		path := filepath.Join(worktree.Filesystem.Root(), "README.md")
+		if err := os.WriteFile(path, []byte(message), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := worktree.Add("README.md"); err != nil {
+			t.Fatalf("Failed to stage file: %v", err)
+		}
+		signature := &object.Signature{Name: "someone", Email: email, When: when}
+		hash, err := worktree.Commit(message, &git.CommitOptions{Author: signature, Committer: signature})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		return hash.String()
+	}
+	first := commit("feat: first", "bnema@example.com", date)
+	commit("fix: someone else", "other@example.com", date.Add(time.Hour))
+	latest := commit("feat: latest\n\nWith a body\n", "BNEMA@example.com", date.Add(2*time.Hour))
+
+	if _, err := git.PlainInit(filepath.Join(root, "empty.git"), true); err != nil {
+		t.Fatalf("Failed to create bare repository: %v", err)
+	}
+	if _, err := git.PlainInit(filepath.Join(root, ".hidden", "skipped"), false); err != nil {
+		t.Fatalf("Failed to create hidden repository: %v", err)
+	}
+
+	provider, err := NewLocalGitProvider([]string{root}, []string{"bnema@example.com"}, true)
+	if err != nil {
+		t.Fatalf("NewLocalGitProvider returned an error: %v", err)
+	}
+	repos, err := provider.ListRepos(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepos returned an error: %v", err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("Expected the working tree and the bare repository, got %+v", repos)
+	}
+	if repos[0].Name != "empty" || !repos[0].Empty {
+		t.Errorf("Expected an empty bare repository, got %+v", repos[0])
+	}
+	gart := repos[1]
+	if gart.Name != "projects/gart" || gart.DefaultBranch != "master" || !gart.IsPrivate {
+		t.Errorf("Unexpected repository %+v", gart)
+	}
+
+	commits, err := provider.ListCommitsSince(context.Background(), gart, time.Time{})
+	if err != nil {
+		t.Fatalf("ListCommitsSince returned an error: %v", err)
+	}
+	if len(commits) != 2 || commits[0].ID != latest || commits[1].ID != first {
+		t.Fatalf("Expected the commits of the configured email, got %+v", commits)
+	}
+	expected := models.Commit{
+		ID:        latest,
+		RepoName:  "projects/gart",
+		Message:   "feat: latest\n\nWith a body",
+		Timestamp: "2024-08-01T14:00:00Z",
+		IsPrivate: true,
+		Forge:     ForgeLocal,
+		Branches:  []string{"master"},
+	}
+	if !reflect.DeepEqual(commits[0], expected) {
+		t.Errorf("Expected commit %+v, got %+v", expected, commits[0])
+	}
+
+	commits, err = provider.ListCommitsSince(context.Background(), gart, date.Add(30*time.Minute))
+	if err != nil || len(commits) != 1 || commits[0].ID != latest {
+		t.Errorf("Expected only the commits after since, got %+v, %v", commits, err)
+	}
+
+	commits, err = provider.ListCommitsByAuthor(context.Background(), gart, "other@example.com", time.Time{})
+	if err != nil || len(commits) != 1 || commits[0].Message != "fix: someone else" {
+		t.Errorf("Expected the commits of the searched author, got %+v, %v", commits, err)
+	}
+}
+
+func TestLocalGitProviderNamesReposByRelativePath(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{filepath.Join("work", "api"), filepath.Join("personal", "api")} {
+		if _, err := git.PlainInit(filepath.Join(root, path), false); err != nil {
+			t.Fatalf("Failed to create repository: %v", err)
+		}
+	}
+
+	provider, err := NewLocalGitProvider([]string{root, filepath.Join(root, "work", "api")}, nil, false)
+	if err != nil {
+		t.Fatalf("NewLocalGitProvider returned an error: %v", err)
+	}
+	repos, err := provider.ListRepos(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepos returned an error: %v", err)
+	}
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+	}
+	if expected := []string{"personal/api", "work/api", "api"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected repos %v, got %v", expected, names)
+	}
+}
+
+func TestLocalMirrorKeepsForgeDetails(t *testing.T) {
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	cache.Merge([]models.Commit{{ID: "a", RepoName: "gordon", Message: "feat: a", Timestamp: "2024-08-01T12:00:00Z", URL: "https://github.com/bnema/gordon/commit/a", Forge: ForgeGitHub}})
+	cache.Merge([]models.Commit{{ID: "a", RepoName: "gordon-mirror", Message: "feat: a", Timestamp: "2024-08-01T12:00:00Z", IsPrivate: true, Forge: ForgeLocal}})
+
+	commit := cache.commits["a"]
+	if commit.URL != "https://github.com/bnema/gordon/commit/a" || commit.RepoName != "gordon" || commit.IsPrivate || commit.Forge != ForgeGitHub {
+		t.Errorf("Expected the forge details to be kept, got %+v", commit)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"portfolio-backend/models"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+const excerptLength = 200
+
+var (
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+	htmlPolicy = newHTMLPolicy()
+)
+
+// newHTMLPolicy allows user generated content plus what the renderer relies on:
+// heading anchors, language classes on fenced code and task list checkboxes
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	return policy
+}
+
+// RenderProject fills the HTML, table of contents and excerpt of a project from its markdown content
+func RenderProject(project *models.Project) error {
+	source := []byte(project.Content)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	var toc []models.TOCEntry
+	excerpt := project.Description
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			id, _ := node.AttributeString("id")
+			idBytes, _ := id.([]byte)
+			toc = append(toc, models.TOCEntry{
+				Level: node.Level,
+				ID:    string(idBytes),
+				Text:  nodeText(node, source),
+			})
+			return ast.WalkSkipChildren, nil
+		case *ast.Paragraph:
+			if excerpt == "" && node.Parent() == doc {
+				excerpt = truncateText(nodeText(node, source), excerptLength)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk markdown of %s: %w", project.Slug, err)
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		return fmt.Errorf("failed to render markdown of %s: %w", project.Slug, err)
+	}
+
+	project.HTML = htmlPolicy.Sanitize(buf.String())
+	project.TOC = toc
+	project.Excerpt = excerpt
+	return nil
+}
+
+// nodeText concatenates the plain text found under a node
+func nodeText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := child.(type) {
+		case *ast.Text:
+			sb.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(node.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(sb.String())
+}
+
+// truncateText shortens s to at most max runes, cutting at a word boundary
+func truncateText(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+
+	runes := []rune(s)[:max]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"portfolio-backend/models"
+)
+
+func TestRenderProject(t *testing.T) {
+	project := models.Project{
+		Slug: "gordon",
+		Content: "# Gordon\n\nA tool to **deploy** web apps in self-hosted environments.\n\n" +
+			"## Install\n\n```go\nfmt.Println(\"hi\")\n```\n\n" +
+			"| Flag | Usage |\n| --- | --- |\n| -v | verbose |\n\n" +
+			"<script>alert('xss')</script>\n\n[click](javascript:alert(1))\n",
+	}
+
+	if err := RenderProject(&project); err != nil {
+		t.Fatalf("RenderProject returned an error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`<h1 id="gordon">Gordon</h1>`,
+		`<h2 id="install">Install</h2>`,
+		`<code class="language-go">`,
+		`<table>`,
+	} {
+		if !strings.Contains(project.HTML, expected) {
+			t.Errorf("Expected HTML to contain %q, got %s", expected, project.HTML)
+		}
+	}
+	for _, unexpected := range []string{"<script", "javascript:"} {
+		if strings.Contains(project.HTML, unexpected) {
+			t.Errorf("Expected HTML to be sanitized of %q, got %s", unexpected, project.HTML)
+		}
+	}
+
+	expectedTOC := []models.TOCEntry{
+		{Level: 1, ID: "gordon", Text: "Gordon"},
+		{Level: 2, ID: "install", Text: "Install"},
+	}
+	if !reflect.DeepEqual(project.TOC, expectedTOC) {
+		t.Errorf("Expected TOC %+v, got %+v", expectedTOC, project.TOC)
+	}
+
+	if project.Excerpt != "A tool to deploy web apps in self-hosted environments." {
+		t.Errorf("Unexpected excerpt %q", project.Excerpt)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	if got := truncateText("a short sentence", 50); got != "a short sentence" {
+		t.Errorf("Expected text to be kept as is, got %q", got)
+	}
+	if got := truncateText("a slightly longer sentence", 12); got != "a slightly…" {
+		t.Errorf("Expected text to be cut at a word boundary, got %q", got)
+	}
+}
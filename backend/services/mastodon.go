@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+const mastodonSourceName = "mastodon"
+
+// mastodonRecentStatuses is how many of the latest statuses every sync fetches again, even
+// those older than the last fetch, so their boost and favourite counts keep up
+const mastodonRecentStatuses = 40
+
+// MastodonSource reads the public statuses of a Mastodon account through the REST API
+type MastodonSource struct {
+	instanceURL string
+	account     string
+	token       string
+	httpClient  *http.Client
+	accountID   string
+}
+
+func NewMastodonSource(instanceURL, account, token string, timeout time.Duration) *MastodonSource {
+	return &MastodonSource{
+		instanceURL: strings.TrimSuffix(instanceURL, "/"),
+		account:     strings.TrimPrefix(account, "@"),
+		token:       token,
+		httpClient:  &http.Client{Timeout: timeout},
+	}
+}
+
+type mastodonAccount struct {
+	ID   string `json:"id"`
+	Acct string `json:"acct"`
+}
+
+type mastodonStatus struct {
+	ID              string          `json:"id"`
+	CreatedAt       time.Time       `json:"created_at"`
+	URL             string          `json:"url"`
+	Content         string          `json:"content"`
+	Visibility      string          `json:"visibility"`
+	ReblogsCount    int             `json:"reblogs_count"`
+	FavouritesCount int             `json:"favourites_count"`
+	Account         mastodonAccount `json:"account"`
+	Reblog          *mastodonStatus `json:"reblog"`
+}
+
+func (s *MastodonSource) Name() string {
+	return mastodonSourceName
+}
+
+func (s *MastodonSource) FetchAll(ctx context.Context) ([]models.Activity, error) {
+	return s.FetchSince(ctx, time.Time{})
+}
+
+// FetchSince pages through the account timeline, newest first, until it reaches statuses older
+// than since past the mastodonRecentStatuses latest ones
+func (s *MastodonSource) FetchSince(ctx context.Context, since time.Time) ([]models.Activity, error) {
+	accountID, err := s.lookupAccountID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var activities []models.Activity
+	seen := 0
+	maxID := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "40")
+		query.Set("exclude_replies", "true")
+		if maxID != "" {
+			query.Set("max_id", maxID)
+		}
+
+		var statuses []mastodonStatus
+		if err := s.get(ctx, "/api/v1/accounts/"+accountID+"/statuses", query, &statuses); err != nil {
+			return nil, fmt.Errorf("failed to fetch statuses: %w", err)
+		}
+		if len(statuses) == 0 {
+			return activities, nil
+		}
+
+		for _, status := range statuses {
+			seen++
+			if seen > mastodonRecentStatuses && !status.CreatedAt.After(since) {
+				// We've reached statuses older than or equal to the last fetch, so we're done
+				return activities, nil
+			}
+			if status.Visibility != "public" {
+				continue
+			}
+			activities = append(activities, statusToActivity(mastodonStatusToTweet(status)))
+		}
+		maxID = statuses[len(statuses)-1].ID
+	}
+}
+
+func (s *MastodonSource) lookupAccountID(ctx context.Context) (string, error) {
+	if s.accountID != "" {
+		return s.accountID, nil
+	}
+
+	query := url.Values{}
+	query.Set("acct", s.account)
+
+	var account mastodonAccount
+	if err := s.get(ctx, "/api/v1/accounts/lookup", query, &account); err != nil {
+		return "", fmt.Errorf("failed to look up account %s: %w", s.account, err)
+	}
+	if account.ID == "" {
+		return "", fmt.Errorf("account %s not found", s.account)
+	}
+
+	s.accountID = account.ID
+	return s.accountID, nil
+}
+
+func (s *MastodonSource) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.instanceURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func mastodonStatusToTweet(status mastodonStatus) models.Tweet {
+	tweet := models.Tweet{
+		ID:           status.ID,
+		UserHandle:   status.Account.Acct,
+		Text:         htmlToText(status.Content),
+		Timestamp:    status.CreatedAt.UTC().Format(time.RFC3339),
+		URL:          status.URL,
+		RetweetCount: status.ReblogsCount,
+		LikeCount:    status.FavouritesCount,
+	}
+
+	// A boost has no content of its own, show the boosted status instead
+	if status.Reblog != nil {
+		tweet.IsRetweet = true
+		tweet.Text = htmlToText(status.Reblog.Content)
+		tweet.URL = status.Reblog.URL
+		tweet.RetweetCount = status.Reblog.ReblogsCount
+		tweet.LikeCount = status.Reblog.FavouritesCount
+	}
+	return tweet
+}
+
+var (
+	lineBreakTags = regexp.MustCompile(`(?i)<br\s*/?>|</p>`)
+	htmlTags      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// htmlToText turns the HTML body of a status into plain text
+func htmlToText(s string) string {
+	s = lineBreakTags.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
+
+func statusToActivity(status models.Tweet) models.Activity {
+	return models.Activity{
+		ID:        status.ID,
+		Type:      models.ActivityTypeTweet,
+		Source:    mastodonSourceName,
+		Content:   status.Text,
+		Timestamp: status.Timestamp,
+		URL:       status.URL,
+		Metadata: map[string]interface{}{
+			"user_handle":   status.UserHandle,
+			"retweet_count": status.RetweetCount,
+			"like_count":    status.LikeCount,
+			"is_retweet":    status.IsRetweet,
+		},
+	}
+}
+
+func activityToStatus(activity models.Activity) models.Tweet {
+	status := models.Tweet{
+		ID:        activity.ID,
+		Text:      activity.Content,
+		Timestamp: activity.Timestamp,
+		URL:       activity.URL,
+	}
+	status.UserHandle, _ = activity.Metadata["user_handle"].(string)
+	status.RetweetCount, _ = activity.Metadata["retweet_count"].(int)
+	status.LikeCount, _ = activity.Metadata["like_count"].(int)
+	status.IsRetweet, _ = activity.Metadata["is_retweet"].(bool)
+	return status
+}
+
+// GetStatusesFromCache returns a page of cached Mastodon statuses, newest first
+func GetStatusesFromCache(page, limit int) ([]models.Tweet, int, error) {
+	activities, totalCount, err := GetActivitiesFromCache(page, limit, []string{mastodonSourceName})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	statuses := make([]models.Tweet, 0, len(activities))
+	for _, activity := range activities {
+		statuses = append(statuses, activityToStatus(activity))
+	}
+	return statuses, totalCount, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMastodonSourceFetchSince(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	statuses := []map[string]interface{}{
+		{
+			"id":               "3",
+			"created_at":       now.Format(time.RFC3339),
+			"url":              "https://mastodon.example/@bnema/3",
+			"content":          "<p>Hello &amp; welcome<br>to the fediverse</p>",
+			"visibility":       "public",
+			"reblogs_count":    2,
+			"favourites_count": 5,
+			"account":          map[string]string{"id": "42", "acct": "bnema"},
+		},
+		{
+			"id":         "2",
+			"created_at": now.Add(-time.Hour).Format(time.RFC3339),
+			"url":        "https://mastodon.example/@bnema/2",
+			"visibility": "public",
+			"account":    map[string]string{"id": "42", "acct": "bnema"},
+			"reblog": map[string]interface{}{
+				"id":               "99",
+				"url":              "https://other.example/@friend/99",
+				"content":          "<p>Boosted post</p>",
+				"reblogs_count":    10,
+				"favourites_count": 20,
+			},
+		},
+		{
+			"id":         "1",
+			"created_at": now.Add(-48 * time.Hour).Format(time.RFC3339),
+			"url":        "https://mastodon.example/@bnema/1",
+			"content":    "<p>Too old</p>",
+			"visibility": "public",
+			"account":    map[string]string{"id": "42", "acct": "bnema"},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/accounts/lookup":
+			if r.URL.Query().Get("acct") != "bnema" {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id": "42", "acct": "bnema"})
+		case "/api/v1/accounts/42/statuses":
+			if r.URL.Query().Get("max_id") != "" {
+				json.NewEncoder(w).Encode([]interface{}{})
+				return
+			}
+			json.NewEncoder(w).Encode(statuses)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := NewMastodonSource(server.URL, "@bnema", "", time.Minute)
+	activities, err := source.FetchSince(context.Background(), now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("FetchSince returned an error: %v", err)
+	}
+
+	// The older status is among the latest ones, fetched again to refresh its counters
+	if len(activities) != 3 {
+		t.Fatalf("Expected 3 activities, got %d", len(activities))
+	}
+
+	status := activityToStatus(activities[0])
+	if status.Text != "Hello & welcome\nto the fediverse" {
+		t.Errorf("Unexpected status text %q", status.Text)
+	}
+	if status.RetweetCount != 2 || status.LikeCount != 5 || status.IsRetweet {
+		t.Errorf("Unexpected counters on status %+v", status)
+	}
+
+	boost := activityToStatus(activities[1])
+	if !boost.IsRetweet || boost.URL != "https://other.example/@friend/99" || boost.Text != "Boosted post" {
+		t.Errorf("Expected boost to point at the boosted status, got %+v", boost)
+	}
+	if boost.RetweetCount != 10 || boost.LikeCount != 20 {
+		t.Errorf("Expected counters of the boosted status, got %+v", boost)
+	}
+}
+
+func TestMastodonSourceFetchSinceStopsPastRecentStatuses(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Hour)
+	page := func(from int) []map[string]interface{} {
+		var statuses []map[string]interface{}
+		for i := from; i > from-mastodonRecentStatuses; i-- {
+			statuses = append(statuses, map[string]interface{}{
+				"id":         strconv.Itoa(i),
+				"created_at": since.Add(-time.Duration(200-i) * time.Minute).Format(time.RFC3339),
+				"visibility": "public",
+				"account":    map[string]string{"id": "42", "acct": "bnema"},
+			})
+		}
+		return statuses
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/accounts/lookup":
+			json.NewEncoder(w).Encode(map[string]string{"id": "42", "acct": "bnema"})
+		case "/api/v1/accounts/42/statuses":
+			requests++
+			switch r.URL.Query().Get("max_id") {
+			case "":
+				json.NewEncoder(w).Encode(page(100))
+			case "61":
+				json.NewEncoder(w).Encode(page(60))
+			default:
+				t.Errorf("Expected paging to stop past the recent statuses, got max_id %s", r.URL.Query().Get("max_id"))
+				json.NewEncoder(w).Encode([]interface{}{})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := NewMastodonSource(server.URL, "bnema", "", time.Minute)
+	activities, err := source.FetchSince(context.Background(), since)
+	if err != nil {
+		t.Fatalf("FetchSince returned an error: %v", err)
+	}
+	if len(activities) != mastodonRecentStatuses || requests != 2 {
+		t.Errorf("Expected the %d latest statuses in 2 requests, got %d in %d", mastodonRecentStatuses, len(activities), requests)
+	}
+}
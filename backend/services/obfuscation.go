@@ -0,0 +1,170 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand"
+	"strings"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// Redaction modes for private commits
+const (
+	// ObfuscationGlyph masks repo name and message with fixed length glyph strings
+	ObfuscationGlyph = "glyph"
+	// ObfuscationLength masks every character but keeps the length and line layout
+	ObfuscationLength = "length"
+	// ObfuscationPlaceholder replaces repo name and message with fixed placeholders
+	ObfuscationPlaceholder = "placeholder"
+	// ObfuscationRepo only hides the repo name, the message stays readable
+	ObfuscationRepo = "repo"
+)
+
+const (
+	privateRepoPlaceholder    = "private"
+	privateMessagePlaceholder = "private work"
+	glyphRepoLength           = 12
+	glyphMessageLength        = 32
+)
+
+var obfuscatedChars = []rune("░▒▓█▄▀■□▢▣▤▥▦▧▨▩▆▅█▉▇▊▄▋▌_▍▃▂▁")
+
+type obfuscationSettings struct {
+	key  []byte
+	mode string
+}
+
+var obfuscation = obfuscationSettings{
+	key:  randomObfuscationKey(),
+	mode: ObfuscationLength,
+}
+
+// InitObfuscation sets the secret and redaction mode used for private commits
+func InitObfuscation(cfg *config.Config) {
+	settings := obfuscationSettings{
+		key:  []byte(cfg.ObfuscationSecret),
+		mode: cfg.ObfuscationMode,
+	}
+	if len(settings.key) == 0 {
+		log.Warn("OBFUSCATION_SECRET is not set, private commit IDs will change on restart")
+		settings.key = randomObfuscationKey()
+	}
+	if settings.mode == "" {
+		settings.mode = ObfuscationLength
+	}
+	obfuscation = settings
+}
+
+func randomObfuscationKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate obfuscation key: %s", err))
+	}
+	return key
+}
+
+// ObfuscatePrivateCommits replaces private commit data with obfuscated strings, after the
+// visibility overrides of the repository rules are applied. The output only depends on the
+// commit, the rules and the server secret, so it is stable across calls.
+func ObfuscatePrivateCommits(commits []models.Commit) []models.Commit {
+	for i, commit := range commits {
+		commits[i] = publicCommit(commit)
+	}
+	return commits
+}
+
+// publicCommit returns a commit as the API shows it
+func publicCommit(commit models.Commit) models.Commit {
+	private, showName := repoRules.visibility(commit)
+	commit.IsPrivate = private
+	if !private {
+		return commit
+	}
+	public := obfuscateCommit(commit)
+	if showName {
+		public.RepoName = commit.RepoName
+	}
+	return public
+}
+
+func obfuscateCommit(commit models.Commit) models.Commit {
+	sha := commit.ID
+	commit.ID = pseudoID(sha)
+	commit.URL = "#"
+	// Branch names tell as much as the repo name, sizes and languages hint at the project,
+	// the account and forge at the organization or the self-hosted instance behind it
+	commit.Branches = nil
+	commit.Changes = nil
+	commit.Language = ""
+	commit.Account = ""
+	commit.Forge = ""
+
+	switch obfuscation.mode {
+	case ObfuscationGlyph:
+		commit.RepoName = glyphString(sha, "repo", glyphRepoLength)
+		commit.Message = glyphString(sha, "message", glyphMessageLength)
+	case ObfuscationPlaceholder:
+		commit.RepoName = privateRepoPlaceholder
+		commit.Message = privateMessagePlaceholder
+	case ObfuscationRepo:
+		commit.RepoName = obfuscateString(sha, "repo", commit.RepoName)
+	default:
+		commit.RepoName = obfuscateString(sha, "repo", commit.RepoName)
+		commit.Message = obfuscateString(sha, "message", commit.Message)
+	}
+	return commit
+}
+
+// keyedHash returns the HMAC of a commit SHA for a given purpose
+func keyedHash(sha, purpose string) []byte {
+	mac := hmac.New(sha256.New, obfuscation.key)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(sha))
+	return mac.Sum(nil)
+}
+
+// pseudoID derives a stable, SHA-looking identifier that cannot be traced back to the commit
+func pseudoID(sha string) string {
+	return hex.EncodeToString(keyedHash(sha, "id"))[:40]
+}
+
+// glyphGenerator returns a generator seeded from the commit, so glyphs are the same on every call
+func glyphGenerator(sha, purpose string) *mathrand.Rand {
+	seed := binary.BigEndian.Uint64(keyedHash(sha, purpose))
+	return mathrand.New(mathrand.NewSource(int64(seed)))
+}
+
+// glyphString returns a glyph string of a fixed length, hiding the length of the original
+func glyphString(sha, purpose string, length int) string {
+	r := glyphGenerator(sha, purpose)
+
+	var result strings.Builder
+	for i := 0; i < length; i++ {
+		result.WriteRune(obfuscatedChars[r.Intn(len(obfuscatedChars))])
+	}
+	return result.String()
+}
+
+// obfuscateString replaces all characters in a string with obfuscated characters
+func obfuscateString(sha, purpose, s string) string {
+	r := glyphGenerator(sha, purpose)
+
+	var result strings.Builder
+	for _, char := range s {
+		if char == ' ' || char == '\n' {
+			result.WriteRune(char)
+		} else {
+			result.WriteRune(obfuscatedChars[r.Intn(len(obfuscatedChars))])
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"portfolio-backend/models"
+)
+
+func TestObfuscatePrivateCommitsIsDeterministic(t *testing.T) {
+	commit := models.Commit{
+		ID:        "0123456789abcdef0123456789abcdef01234567",
+		RepoName:  "secret-repo",
+		Message:   "feat: top secret\n\nDetails",
+		Timestamp: "2024-08-01T12:00:00Z",
+		URL:       "https://github.com/bnema/secret-repo/commit/0123456",
+		IsPrivate: true,
+		Changes:   &models.CommitChanges{Additions: 120, Deletions: 4, FilesChanged: 3},
+		Language:  "Go",
+		Forge:     ForgeGitLab,
+		Account:   "work",
+	}
+	public := models.Commit{ID: "public", RepoName: "gordon", Message: "fix: typo", URL: "https://github.com/bnema/gordon"}
+
+	originalSettings := obfuscation
+	defer func() { obfuscation = originalSettings }()
+
+	for _, mode := range []string{ObfuscationGlyph, ObfuscationLength, ObfuscationPlaceholder, ObfuscationRepo} {
+		obfuscation = obfuscationSettings{key: []byte("server-secret"), mode: mode}
+
+		first := ObfuscatePrivateCommits([]models.Commit{commit, public})
+		second := ObfuscatePrivateCommits([]models.Commit{commit, public})
+		if !reflect.DeepEqual(first[0], second[0]) {
+			t.Errorf("[%s] Expected stable obfuscation, got %+v and %+v", mode, first[0], second[0])
+		}
+		if !reflect.DeepEqual(first[1], public) {
+			t.Errorf("[%s] Expected public commit to be untouched, got %+v", mode, first[1])
+		}
+
+		obfuscated := first[0]
+		if obfuscated.ID == commit.ID || len(obfuscated.ID) != 40 {
+			t.Errorf("[%s] Expected a 40 character pseudo ID, got %q", mode, obfuscated.ID)
+		}
+		if obfuscated.URL != "#" || strings.Contains(obfuscated.RepoName, "secret") {
+			t.Errorf("[%s] Expected repo name and URL to be hidden, got %+v", mode, obfuscated)
+		}
+		if obfuscated.Changes != nil || obfuscated.Language != "" {
+			t.Errorf("[%s] Expected commit size and language to be hidden, got %+v", mode, obfuscated)
+		}
+		if obfuscated.Account != "" || obfuscated.Forge != "" {
+			t.Errorf("[%s] Expected the account and forge to be hidden, got %+v", mode, obfuscated)
+		}
+
+		switch mode {
+		case ObfuscationGlyph:
+			if utf8.RuneCountInString(obfuscated.Message) != glyphMessageLength {
+				t.Errorf("Expected a fixed length message, got %q", obfuscated.Message)
+			}
+		case ObfuscationLength:
+			if utf8.RuneCountInString(obfuscated.Message) != utf8.RuneCountInString(commit.Message) || strings.Count(obfuscated.Message, "\n") != 2 {
+				t.Errorf("Expected length and line breaks to be kept, got %q", obfuscated.Message)
+			}
+		case ObfuscationPlaceholder:
+			if obfuscated.Message != privateMessagePlaceholder || obfuscated.RepoName != privateRepoPlaceholder {
+				t.Errorf("Expected placeholders, got %+v", obfuscated)
+			}
+		case ObfuscationRepo:
+			if obfuscated.Message != commit.Message {
+				t.Errorf("Expected message to stay readable, got %q", obfuscated.Message)
+			}
+		}
+	}
+
+	// Another secret yields other pseudo IDs
+	obfuscation = obfuscationSettings{key: []byte("server-secret"), mode: ObfuscationLength}
+	withFirstKey := ObfuscatePrivateCommits([]models.Commit{commit})[0].ID
+	obfuscation = obfuscationSettings{key: []byte("another-secret"), mode: ObfuscationLength}
+	if ObfuscatePrivateCommits([]models.Commit{commit})[0].ID == withFirstKey {
+		t.Error("Expected pseudo IDs to depend on the secret")
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+)
+
+type cachedContentFile struct {
+	ContentFile
+	content []byte
+}
+
+// ProjectCache keeps the parsed projects along with the raw files and their blob SHAs,
+// so a refresh only downloads the files that changed
+type ProjectCache struct {
+	files      map[string]cachedContentFile
+	projects   []models.Project
+	fileErrors []*ProjectFileError
+	loaded     bool
+	mutex      sync.RWMutex
+	// refreshMutex serializes refreshes without blocking readers
+	refreshMutex sync.Mutex
+}
+
+var projectCache = NewProjectCache()
+
+// projectRefreshTimeout bounds a refresh of the project cache, InitContentProvider applies
+// the configured one
+var projectRefreshTimeout = config.Defaults().ProjectRefreshTimeout
+
+func NewProjectCache() *ProjectCache {
+	return &ProjectCache{
+		files: make(map[string]cachedContentFile),
+	}
+}
+
+// Refresh lists the project files and refetches those whose SHA changed since the last refresh
+func (c *ProjectCache) Refresh(ctx context.Context) error {
+	provider := GetContentProvider()
+	if provider == nil {
+		return errors.New("content provider is not initialized")
+	}
+
+	c.refreshMutex.Lock()
+	defer c.refreshMutex.Unlock()
+
+	listed, err := provider.ListFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.RLock()
+	previous := c.files
+	c.mutex.RUnlock()
+
+	files := make(map[string]cachedContentFile, len(listed))
+	ordered := make([]cachedContentFile, 0, len(listed))
+	changed := make(map[string]bool)
+	for _, file := range listed {
+		if filepath.Ext(file.Name) != ".md" {
+			continue
+		}
+
+		cached, ok := previous[file.Path]
+		if !ok || file.SHA == "" || cached.SHA != file.SHA {
+			content, err := provider.ReadFile(ctx, file)
+			if err != nil {
+				return err
+			}
+			cached = cachedContentFile{ContentFile: file, content: content}
+			changed[file.Name] = true
+		}
+
+		files[file.Path] = cached
+		ordered = append(ordered, cached)
+	}
+
+	projects, fileErrors := buildProjects(ordered)
+	for _, fileErr := range fileErrors {
+		// Unchanged files were already reported on a previous refresh
+		if changed[fileErr.File] {
+			log.Warn("Skipping invalid project file", "file", fileErr.File, "error", fileErr.Err)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.files = files
+	c.projects = projects
+	c.fileErrors = fileErrors
+	c.loaded = true
+
+	if len(changed) > 0 || len(files) != len(previous) {
+		log.Info("Project cache updated", "projects", len(projects), "fetched_files", len(changed))
+	}
+	return nil
+}
+
+// GetProjects returns a copy of the cached projects, refreshing the cache if it was never loaded
+func (c *ProjectCache) GetProjects(ctx context.Context) ([]models.Project, error) {
+	c.mutex.RLock()
+	loaded := c.loaded
+	c.mutex.RUnlock()
+
+	if !loaded {
+		if err := c.Refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	projects := make([]models.Project, len(c.projects))
+	copy(projects, c.projects)
+	return projects, nil
+}
+
+func UpdateProjectCache() error {
+	ctx, cancel := context.WithTimeout(context.Background(), projectRefreshTimeout)
+	defer cancel()
+
+	return projectCache.Refresh(ctx)
+}
+
+func StartProjectCacheScheduler(interval time.Duration) {
+	if err := UpdateProjectCache(); err != nil {
+		log.Error("Error initializing project cache", "error", err)
+	}
+
+	// Unchanged content is answered with 304 Not Modified, so frequent refreshes are cheap
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := UpdateProjectCache(); err != nil {
+				log.Error("Error updating project cache", "error", err)
+			}
+		}
+	}()
+}
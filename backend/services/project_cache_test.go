@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v63/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestProjectCacheRevalidatesWithETag(t *testing.T) {
+	listing := []*github.RepositoryContent{
+		{Type: github.String("file"), Name: github.String("gart.md"), Path: github.String("projects/gart.md"), SHA: github.String("sha-gart")},
+		{Type: github.String("file"), Name: github.String("gordon.md"), Path: github.String("projects/gordon.md"), SHA: github.String("sha-gordon")},
+	}
+	blobs := map[string]string{
+		"sha-gart":    "# Gart\n",
+		"sha-gordon":  "# Gordon\n",
+		"sha-gordon2": "# Gordon 2\n",
+	}
+
+	var listCalls, notModified, blobCalls atomic.Int32
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				listCalls.Add(1)
+				etag := `"` + listing[1].GetSHA() + `"`
+				if r.Header.Get("If-None-Match") == etag {
+					notModified.Add(1)
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", etag)
+				json.NewEncoder(w).Encode(listing)
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposGitBlobsByOwnerByRepoByFileSha,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				blobCalls.Add(1)
+				sha := r.URL.Path[len("/repos/bnema/portfolio-mono/git/blobs/"):]
+				w.Write([]byte(blobs[sha]))
+			}),
+		),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(mockedHTTPClient)
+	defer func() { githubClient = originalClient }()
+
+	originalProvider := contentProvider
+	contentProvider = NewGitHubContentProvider("bnema", "portfolio-mono", "projects")
+	defer func() { contentProvider = originalProvider }()
+
+	c := NewProjectCache()
+	ctx := context.Background()
+
+	// Initial load fetches every file, the second refresh is answered with 304
+	for i := 0; i < 2; i++ {
+		if err := c.Refresh(ctx); err != nil {
+			t.Fatalf("Refresh returned an error: %v", err)
+		}
+	}
+	if listCalls.Load() != 2 || notModified.Load() != 1 {
+		t.Errorf("Expected 2 listings with 1 revalidated, got %d listings and %d revalidated", listCalls.Load(), notModified.Load())
+	}
+	if blobCalls.Load() != 2 {
+		t.Errorf("Expected 2 blob fetches, got %d", blobCalls.Load())
+	}
+
+	// Only the file whose SHA changed is fetched again
+	listing[1].SHA = github.String("sha-gordon2")
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh returned an error: %v", err)
+	}
+	if blobCalls.Load() != 3 {
+		t.Errorf("Expected 3 blob fetches, got %d", blobCalls.Load())
+	}
+
+	projects, err := c.GetProjects(ctx)
+	if err != nil {
+		t.Fatalf("GetProjects returned an error: %v", err)
+	}
+	if len(projects) != 2 || projects[1].Content != "# Gordon 2\n" {
+		t.Errorf("Expected the updated Gordon content, got %+v", projects)
+	}
+}
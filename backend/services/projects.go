@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"portfolio-backend/models"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrProjectNotFound is returned when no project matches a slug
+var ErrProjectNotFound = errors.New("project not found")
+
+// ProjectFileError reports a project file that could not be loaded
+type ProjectFileError struct {
+	File string
+	Err  error
+}
+
+func (e *ProjectFileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File, e.Err)
+}
+
+func (e *ProjectFileError) Unwrap() error {
+	return e.Err
+}
+
+// FetchProjectsContent returns all published projects from the project cache,
+// loading it on first use
+func FetchProjectsContent(ctx context.Context) ([]models.Project, error) {
+	return projectCache.GetProjects(ctx)
+}
+
+// loadProjects reads and validates every project file without caching, returning the
+// published projects sorted by order and title along with the errors found in each invalid file
+func loadProjects(ctx context.Context) ([]models.Project, []*ProjectFileError, error) {
+	c := NewProjectCache()
+	if err := c.Refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+	return c.projects, c.fileErrors, nil
+}
+
+// buildProjects parses the project files in listing order, the first file claiming a slug keeps it
+func buildProjects(files []cachedContentFile) ([]models.Project, []*ProjectFileError) {
+	var projects []models.Project
+	var fileErrors []*ProjectFileError
+	// Slugs and aliases claimed so far, mapped to the file claiming them
+	claimed := make(map[string]string)
+	for _, file := range files {
+		project, published, err := parseProject(file.Name, string(file.content))
+		if err != nil {
+			fileErrors = append(fileErrors, &ProjectFileError{File: file.Name, Err: err})
+			continue
+		}
+
+		if err := claimSlugs(claimed, file.Name, project); err != nil {
+			fileErrors = append(fileErrors, &ProjectFileError{File: file.Name, Err: err})
+			continue
+		}
+		if published {
+			projects = append(projects, project)
+		}
+	}
+
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].Order != projects[j].Order {
+			return projects[i].Order < projects[j].Order
+		}
+		return projects[i].Title < projects[j].Title
+	})
+
+	return projects, fileErrors
+}
+
+// claimSlugs records the slug and aliases of a project, failing if another file already uses one of them
+func claimSlugs(claimed map[string]string, fileName string, project models.Project) error {
+	slugs := append([]string{project.Slug}, project.Aliases...)
+
+	var errs []error
+	seen := make(map[string]bool, len(slugs))
+	for _, slug := range slugs {
+		if seen[slug] {
+			errs = append(errs, fmt.Errorf("slug %q is declared twice", slug))
+		} else if owner, ok := claimed[slug]; ok {
+			errs = append(errs, fmt.Errorf("slug %q is already used by %s", slug, owner))
+		}
+		seen[slug] = true
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, slug := range slugs {
+		claimed[slug] = fileName
+	}
+	return nil
+}
+
+// GetProjectBySlug returns the project with the given slug. When the slug is an alias
+// of a project, the project is returned along with redirected set to true.
+func GetProjectBySlug(ctx context.Context, slug string) (project models.Project, redirected bool, err error) {
+	projects, err := FetchProjectsContent(ctx)
+	if err != nil {
+		return models.Project{}, false, err
+	}
+
+	for _, p := range projects {
+		if p.Slug == slug {
+			return p, false, nil
+		}
+	}
+	for _, p := range projects {
+		for _, alias := range p.Aliases {
+			if alias == slug {
+				return p, true, nil
+			}
+		}
+	}
+	return models.Project{}, false, ErrProjectNotFound
+}
+
+// projectFrontMatter is the metadata block at the top of a project file
+type projectFrontMatter struct {
+	Title         string    `yaml:"title" toml:"title"`
+	Slug          string    `yaml:"slug" toml:"slug"`
+	Aliases       []string  `yaml:"aliases" toml:"aliases"`
+	Description   string    `yaml:"description" toml:"description"`
+	Tags          []string  `yaml:"tags" toml:"tags"`
+	TechStack     []string  `yaml:"tech_stack" toml:"tech_stack"`
+	RepoURL       string    `yaml:"repo_url" toml:"repo_url"`
+	DemoURL       string    `yaml:"demo_url" toml:"demo_url"`
+	CoverImage    string    `yaml:"cover_image" toml:"cover_image"`
+	Status        string    `yaml:"status" toml:"status"`
+	Order         int       `yaml:"order" toml:"order"`
+	Published     *bool     `yaml:"published" toml:"published"`
+	Draft         bool      `yaml:"draft" toml:"draft"`
+	Date          time.Time `yaml:"date" toml:"date"`
+	ProjectOrigin string    `yaml:"project_origin" toml:"project_origin"`
+}
+
+var (
+	projectStatuses = map[string]bool{"": true, "active": true, "maintained": true, "wip": true, "archived": true}
+	slugPattern     = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// parseProject builds a project from a markdown file name and its content, reporting
+// whether the project is published
+func parseProject(fileName, contentStr string) (models.Project, bool, error) {
+	var meta projectFrontMatter
+	body, err := splitFrontMatter(contentStr, &meta)
+	if err != nil {
+		return models.Project{}, false, err
+	}
+
+	// Legacy single line metadata, kept for files without front matter
+	lines := strings.Split(body, "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "[project_origin]:") {
+		meta.ProjectOrigin = strings.TrimSpace(strings.TrimPrefix(lines[0], "[project_origin]:"))
+		body = strings.Join(lines[1:], "\n")
+	}
+
+	fileTitle := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	if meta.Title == "" {
+		meta.Title = fileTitle
+	}
+	if meta.Slug == "" {
+		meta.Slug = strings.ToLower(strings.ReplaceAll(fileTitle, " ", "-"))
+	}
+
+	if err := meta.validate(); err != nil {
+		return models.Project{}, false, err
+	}
+
+	project := models.Project{
+		Title:         meta.Title,
+		Slug:          meta.Slug,
+		Aliases:       meta.Aliases,
+		Description:   meta.Description,
+		Tags:          meta.Tags,
+		TechStack:     meta.TechStack,
+		RepoURL:       meta.RepoURL,
+		DemoURL:       meta.DemoURL,
+		CoverImage:    meta.CoverImage,
+		Status:        meta.Status,
+		Order:         meta.Order,
+		ProjectOrigin: meta.ProjectOrigin,
+		Content:       body,
+	}
+	if !meta.Date.IsZero() {
+		project.Date = meta.Date.Format("2006-01-02")
+	}
+
+	published := !meta.Draft && (meta.Published == nil || *meta.Published)
+	return project, published, nil
+}
+
+// splitFrontMatter decodes a leading YAML (---) or TOML (+++) block into meta and returns the remaining body
+func splitFrontMatter(content string, meta *projectFrontMatter) (string, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	var delimiter string
+	switch {
+	case strings.HasPrefix(content, "---\n"), strings.HasPrefix(content, "---\r\n"):
+		delimiter = "---"
+	case strings.HasPrefix(content, "+++\n"), strings.HasPrefix(content, "+++\r\n"):
+		delimiter = "+++"
+	default:
+		return content, nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == delimiter {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return "", fmt.Errorf("front matter is not closed by %s", delimiter)
+	}
+
+	raw := strings.Join(lines[1:end], "")
+	body := strings.Join(lines[end+1:], "")
+
+	if delimiter == "---" {
+		decoder := yaml.NewDecoder(strings.NewReader(raw))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(meta); err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("invalid YAML front matter: %w", err)
+		}
+		return body, nil
+	}
+
+	md, err := toml.NewDecoder(bytes.NewBufferString(raw)).Decode(meta)
+	if err != nil {
+		return "", fmt.Errorf("invalid TOML front matter: %w", err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return "", fmt.Errorf("invalid TOML front matter: unknown field %s", undecoded[0])
+	}
+	return body, nil
+}
+
+// validate lists every problem found in the metadata
+func (m *projectFrontMatter) validate() error {
+	var errs []error
+	if strings.TrimSpace(m.Title) == "" {
+		errs = append(errs, errors.New("title must not be empty"))
+	}
+	if !slugPattern.MatchString(m.Slug) {
+		errs = append(errs, fmt.Errorf("slug %q must only contain lowercase letters, digits and dashes", m.Slug))
+	}
+	for _, alias := range m.Aliases {
+		if !slugPattern.MatchString(alias) {
+			errs = append(errs, fmt.Errorf("alias %q must only contain lowercase letters, digits and dashes", alias))
+		}
+	}
+	if !projectStatuses[m.Status] {
+		errs = append(errs, fmt.Errorf("unknown status %q", m.Status))
+	}
+	if m.Order < 0 {
+		errs = append(errs, errors.New("order must not be negative"))
+	}
+	for _, field := range []struct{ name, value string }{{"repo_url", m.RepoURL}, {"demo_url", m.DemoURL}} {
+		name, value := field.name, field.value
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s %q is not an absolute http(s) URL", name, value))
+		}
+	}
+	return errors.Join(errs...)
+}
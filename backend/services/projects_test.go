@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"portfolio-backend/models"
+)
+
+func TestLoadProjectsFrontMatter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"gordon.md": {Data: []byte(`---
+title: Gordon
+slug: gordon
+description: Deploy web apps in self-hosted environments
+tags: [docker, traefik]
+tech_stack: [Go, HTMX]
+repo_url: https://github.com/bnema/gordon
+status: active
+order: 1
+date: 2024-03-01
+---
+# Gordon
+`)},
+		"gart.md": {Data: []byte(`+++
+title = "Gart"
+tags = ["dotfiles"]
+order = 2
+date = 2023-11-20
++++
+# Gart
+`)},
+		"legacy.md":   {Data: []byte("[project_origin]: github\n# Legacy\n")},
+		"draft.md":    {Data: []byte("---\ntitle: Draft\ndraft: true\n---\nWork in progress\n")},
+		"hidden.md":   {Data: []byte("---\npublished: false\n---\nNot yet\n")},
+		"broken.md":   {Data: []byte("---\ntitle: Broken\nstatus: unknown\nrepo_url: not-a-url\n---\n")},
+		"typo.md":     {Data: []byte("---\ntitel: Typo\n---\n")},
+		"unclosed.md": {Data: []byte("---\ntitle: Unclosed\n")},
+	}
+
+	originalProvider := contentProvider
+	contentProvider = NewFSContentProvider(fsys, ".")
+	defer func() { contentProvider = originalProvider }()
+
+	projects, fileErrors, err := loadProjects(context.Background())
+	if err != nil {
+		t.Fatalf("loadProjects returned an error: %v", err)
+	}
+
+	expected := []models.Project{
+		{
+			Title:         "legacy",
+			Slug:          "legacy",
+			Content:       "# Legacy\n",
+			ProjectOrigin: "github",
+		},
+		{
+			Title:       "Gordon",
+			Slug:        "gordon",
+			Description: "Deploy web apps in self-hosted environments",
+			Tags:        []string{"docker", "traefik"},
+			TechStack:   []string{"Go", "HTMX"},
+			RepoURL:     "https://github.com/bnema/gordon",
+			Status:      "active",
+			Order:       1,
+			Date:        "2024-03-01",
+			Content:     "# Gordon\n",
+		},
+		{
+			Title:   "Gart",
+			Slug:    "gart",
+			Tags:    []string{"dotfiles"},
+			Order:   2,
+			Date:    "2023-11-20",
+			Content: "# Gart\n",
+		},
+	}
+	if !reflect.DeepEqual(projects, expected) {
+		t.Errorf("Expected projects %+v, got %+v", expected, projects)
+	}
+
+	invalid := map[string]string{}
+	for _, fileErr := range fileErrors {
+		invalid[fileErr.File] = fileErr.Error()
+	}
+	if len(invalid) != 3 {
+		t.Fatalf("Expected 3 invalid files, got %v", invalid)
+	}
+	if msg := invalid["broken.md"]; !strings.Contains(msg, "unknown status") || !strings.Contains(msg, "repo_url") {
+		t.Errorf("Expected every problem of broken.md to be reported, got %q", msg)
+	}
+	if _, ok := invalid["typo.md"]; !ok {
+		t.Error("Expected unknown front matter fields to be rejected")
+	}
+	if _, ok := invalid["unclosed.md"]; !ok {
+		t.Error("Expected unclosed front matter to be rejected")
+	}
+}
+
+func TestGetProjectBySlug(t *testing.T) {
+	fsys := fstest.MapFS{
+		"gart.md":      {Data: []byte("---\ntitle: Gart\naliases: [dotfiles-manager]\n---\n# Gart\n")},
+		"new-gart.md":  {Data: []byte("---\ntitle: Gart v2\nslug: gart\n---\n# Gart v2\n")},
+		"gordon.md":    {Data: []byte("---\ntitle: Gordon\naliases: [dotfiles-manager]\n---\n# Gordon\n")},
+		"portfolio.md": {Data: []byte("# Portfolio\n")},
+	}
+
+	originalProvider := contentProvider
+	contentProvider = NewFSContentProvider(fsys, ".")
+	defer func() { contentProvider = originalProvider }()
+
+	originalCache := projectCache
+	projectCache = NewProjectCache()
+	defer func() { projectCache = originalCache }()
+
+	_, fileErrors, err := loadProjects(context.Background())
+	if err != nil {
+		t.Fatalf("loadProjects returned an error: %v", err)
+	}
+	if len(fileErrors) != 2 {
+		t.Fatalf("Expected slug collisions in 2 files, got %v", fileErrors)
+	}
+	for _, fileErr := range fileErrors {
+		if !strings.Contains(fileErr.Error(), "already used by") {
+			t.Errorf("Expected a collision error, got %q", fileErr.Error())
+		}
+	}
+
+	project, redirected, err := GetProjectBySlug(context.Background(), "gart")
+	if err != nil || redirected || project.Title != "Gart" {
+		t.Errorf("Expected Gart without redirect, got %+v (redirected=%v, err=%v)", project, redirected, err)
+	}
+
+	project, redirected, err = GetProjectBySlug(context.Background(), "dotfiles-manager")
+	if err != nil || !redirected || project.Slug != "gart" {
+		t.Errorf("Expected alias to redirect to gart, got %+v (redirected=%v, err=%v)", project, redirected, err)
+	}
+
+	if _, _, err = GetProjectBySlug(context.Background(), "unknown"); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Expected ErrProjectNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// Forges commits are tagged with
+const (
+	ForgeGitHub   = "github"
+	ForgeGitLab   = "gitlab"
+	ForgeGitea    = "gitea"
+	ForgeForgejo  = "forgejo"
+	ForgeCodeberg = "codeberg"
+)
+
+// CommitProvider reads the repositories and commits of an account on a code forge
+type CommitProvider interface {
+	// Name identifies the account in logs, such as gitlab:gitlab.com
+	Name() string
+	// Forge is the kind of forge the commits are tagged with
+	Forge() string
+	// ListRepos returns the repositories of the account
+	ListRepos(ctx context.Context) ([]ForgeRepo, error)
+	// ListCommitsSince returns the commits of the default branch of a repository made
+	// after since, newest first. A zero since means every commit.
+	ListCommitsSince(ctx context.Context, repo ForgeRepo, since time.Time) ([]models.Commit, error)
+	// ListCommitsByAuthor returns the commits of the default branch of a repository made
+	// after since by the given name, email or login, newest first
+	ListCommitsByAuthor(ctx context.Context, repo ForgeRepo, author string, since time.Time) ([]models.Commit, error)
+}
+
+// ForgeRepo is a repository as listed by a CommitProvider
+type ForgeRepo struct {
+	// ID is how the forge API addresses the repository
+	ID            string
+	Owner         string
+	Name          string
+	IsPrivate     bool
+	DefaultBranch string
+	// Empty repositories have no commit to list
+	Empty    bool
+	Archived bool
+	Fork     bool
+}
+
+func (r ForgeRepo) fullName() string {
+	if r.Owner == "" {
+		return r.Name
+	}
+	return r.Owner + "/" + r.Name
+}
+
+// providerAccount is a configured account along with its last successful sync, persisted
+// with the commit cache under key
+type providerAccount struct {
+	provider CommitProvider
+	author   string
+	key      string
+	state    models.ProviderSyncState
+}
+
+// newProviderAccount restores the last sync of an account, so a restart only fetches the
+// commits made since. Accounts are told apart by provider name and author.
+func newProviderAccount(provider CommitProvider, author string) *providerAccount {
+	key := provider.Name()
+	if author != "" {
+		key += ":" + author
+	}
+	return &providerAccount{provider: provider, author: author, key: key, state: cache.ProviderState(key)}
+}
+
+// previousSync returns the last sync of a repository, empty when it never was synced
+func (a *providerAccount) previousSync(fullName string) string {
+	// States saved before repositories were tracked only know the account
+	if a.state.Repos == nil {
+		return a.state.SyncedAt
+	}
+	return a.state.Repos[fullName]
+}
+
+// since returns the time the commits of a repository are fetched from, the zero time for a
+// repository never synced
+func (a *providerAccount) since(fullName string) time.Time {
+	syncedAt, err := time.Parse(time.RFC3339, a.previousSync(fullName))
+	if err != nil {
+		return time.Time{}
+	}
+	// Commit dates come from the committer's clock, leave some slack
+	return syncedAt.Add(-syncOverlap)
+}
+
+var (
+	commitProviders      []*providerAccount
+	commitProvidersMutex sync.Mutex
+)
+
+// NewCommitProvider creates the provider of a configured forge account, its API requests
+// bounded by timeout
+func NewCommitProvider(account config.ForgeAccount, timeout time.Duration) (CommitProvider, error) {
+	switch account.Forge {
+	case ForgeGitHub:
+		return NewGitHubProvider(account.URL, account.Token)
+	case ForgeGitLab:
+		return NewGitLabProvider(account.URL, account.Token, timeout), nil
+	case ForgeGitea, ForgeForgejo, ForgeCodeberg:
+		return NewGiteaProvider(account.Forge, account.URL, account.Token, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown forge: %s", account.Forge)
+	}
+}
+
+// InitCommitProviders registers the forge accounts and local directories commits are synced
+// from besides GitHub. The commit cache must be initialized first, it holds their last sync.
+func InitCommitProviders(cfg *config.Config) error {
+	var accounts []*providerAccount
+	for _, account := range cfg.ForgeAccounts {
+		provider, err := NewCommitProvider(account, cfg.HTTPTimeout)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, newProviderAccount(provider, account.Author))
+		log.Info("Commit provider registered", "provider", provider.Name())
+	}
+	if len(cfg.LocalRepoDirs) > 0 {
+		provider, err := NewLocalGitProvider(cfg.LocalRepoDirs, cfg.LocalAuthorEmails, cfg.LocalReposPrivate)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, newProviderAccount(provider, ""))
+		log.Info("Commit provider registered", "provider", provider.Name(), "dirs", len(cfg.LocalRepoDirs))
+	}
+
+	commitProvidersMutex.Lock()
+	commitProviders = accounts
+	commitProvidersMutex.Unlock()
+	return nil
+}
+
+func hasCommitProviders() bool {
+	commitProvidersMutex.Lock()
+	defer commitProvidersMutex.Unlock()
+	return len(commitProviders) > 0
+}
+
+// SyncCommitProviders fetches the new commits of every registered account and hands them
+// to publish. An account whose repositories cannot be listed is fetched again from its last
+// successful sync.
+func SyncCommitProviders(ctx context.Context, publish func([]models.Commit)) error {
+	commitProvidersMutex.Lock()
+	defer commitProvidersMutex.Unlock()
+
+	var errs []error
+	for _, account := range commitProviders {
+		commits, state, err := fetchProviderCommits(ctx, account)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", account.provider.Name(), err))
+			continue
+		}
+		if len(commits) > 0 {
+			publish(commits)
+		}
+		account.state = state
+		cache.SaveProviderState(state)
+		log.Info("Commit provider synced", "provider", account.provider.Name(), "new_commits", len(commits))
+	}
+	return errors.Join(errs...)
+}
+
+// fetchProviderCommits returns the commits of an account made since the last sync of each
+// repository, only those of its author when one is configured, along with the new state of
+// the account. Like the crawler, it skips a repository failing on its own, which keeps its
+// previous state and is fetched again by the next sync, and only fails when the repositories
+// cannot be listed. Cached commits of repositories no longer listed are removed.
+func fetchProviderCommits(ctx context.Context, account *providerAccount) ([]models.Commit, models.ProviderSyncState, error) {
+	provider := account.provider
+	startedAt := time.Now().UTC().Format(time.RFC3339)
+	repos, err := provider.ListRepos(ctx)
+	if err != nil {
+		return nil, account.state, err
+	}
+
+	state := models.ProviderSyncState{Key: account.key, SyncedAt: startedAt, Repos: make(map[string]string, len(repos))}
+	var commits []models.Commit
+	for _, repo := range repos {
+		fullName := repo.fullName()
+		if !repoRules.Allows(fullName, repo.Archived, repo.Fork) {
+			continue
+		}
+		// Empty repositories stay unsynced, their first commits may be older than this sync
+		state.Repos[fullName] = account.previousSync(fullName)
+		if repo.Empty {
+			continue
+		}
+
+		since := account.since(fullName)
+		var repoCommits []models.Commit
+		if account.author != "" {
+			repoCommits, err = provider.ListCommitsByAuthor(ctx, repo, account.author, since)
+		} else {
+			repoCommits, err = provider.ListCommitsSince(ctx, repo, since)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, account.state, ctx.Err()
+			}
+			log.Warn("Failed to list commits of repository", "provider", provider.Name(), "repo", repo.Name, "error", err)
+			continue
+		}
+		state.Repos[fullName] = startedAt
+		commits = append(commits, repoCommits...)
+	}
+
+	// Repositories left over were deleted, transferred away or are now excluded by the rules
+	for fullName := range account.state.Repos {
+		if _, listed := state.Repos[fullName]; listed {
+			continue
+		}
+		var ids []string
+		for _, commit := range cache.commitsOfForgeRepo(provider.Forge(), fullName) {
+			ids = append(ids, commit.ID)
+		}
+		if len(ids) > 0 {
+			cache.Remove(ids)
+		}
+	}
+	return commits, state, nil
+}
+
+// forgeStatusError is an unexpected HTTP status returned by a forge API
+type forgeStatusError struct {
+	StatusCode int
+	Status     string
+	Path       string
+}
+
+func (e *forgeStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %s from %s", e.Status, e.Path)
+}
+
+func isForgeStatus(err error, code int) bool {
+	var statusErr *forgeStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == code
+}
+
+// forgeAPI is a minimal JSON client for the REST API of a forge
+type forgeAPI struct {
+	baseURL    string
+	authHeader string
+	authValue  string
+	httpClient *http.Client
+}
+
+func newForgeAPI(baseURL, authHeader, authValue string, timeout time.Duration) forgeAPI {
+	return forgeAPI{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		authHeader: authHeader,
+		authValue:  authValue,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// get decodes the JSON response of a GET request into v and returns the response headers,
+// which forges use for pagination
+func (a forgeAPI) get(ctx context.Context, path string, query url.Values, v interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if a.authValue != "" {
+		req.Header.Set(a.authHeader, a.authValue)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &forgeStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Path: path}
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// matchAuthor reports whether a commit author matches a name, email or login, ignoring case
+func matchAuthor(author string, identities ...string) bool {
+	for _, identity := range identities {
+		if identity != "" && strings.EqualFold(identity, author) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf returns the host of an instance URL, used to name providers
+func hostOf(instanceURL string) string {
+	u, err := url.Parse(instanceURL)
+	if err != nil || u.Host == "" {
+		return instanceURL
+	}
+	return u.Host
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+)
+
+func TestSyncCommitProviders(t *testing.T) {
+	var forgejoURL string
+	var forgejoSince, brokenSince []string
+	oldListed := true
+	forgejoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/user":
+			json.NewEncoder(w).Encode(map[string]string{"login": "bnema"})
+		case "/api/v1/user/repos":
+			if r.URL.Query().Get("page") != "1" {
+				json.NewEncoder(w).Encode([]interface{}{})
+				return
+			}
+			repos := []map[string]interface{}{
+				{"id": 1, "name": "gordon", "default_branch": "main", "owner": map[string]string{"login": "bnema"}},
+				{"id": 2, "name": "empty", "empty": true, "owner": map[string]string{"login": "bnema"}},
+				{"id": 3, "name": "broken", "default_branch": "main", "owner": map[string]string{"login": "bnema"}},
+			}
+			if oldListed {
+				repos = append(repos, map[string]interface{}{"id": 4, "name": "old", "default_branch": "main", "owner": map[string]string{"login": "bnema"}})
+			}
+			json.NewEncoder(w).Encode(repos)
+		case "/api/v1/repos/bnema/gordon/commits":
+			forgejoSince = append(forgejoSince, r.URL.Query().Get("since"))
+			w.Header().Set("X-HasMore", "false")
+			json.NewEncoder(w).Encode([]map[string]interface{}{{
+				"sha":      "shared",
+				"html_url": "https://forgejo.example/bnema/gordon/commit/shared",
+				"commit": map[string]interface{}{
+					"message": "feat: mirrored",
+					"author":  map[string]string{"date": time.Now().UTC().Format(time.RFC3339)},
+				},
+			}})
+		case "/api/v1/repos/bnema/broken/commits":
+			brokenSince = append(brokenSince, r.URL.Query().Get("since"))
+			w.WriteHeader(http.StatusForbidden)
+		case "/api/v1/repos/bnema/old/commits":
+			w.Header().Set("X-HasMore", "false")
+			json.NewEncoder(w).Encode([]map[string]interface{}{{
+				"sha":      "o1",
+				"html_url": forgejoURL + "/bnema/old/commit/o1",
+				"commit": map[string]interface{}{
+					"message": "feat: old",
+					"author":  map[string]string{"date": time.Now().UTC().Format(time.RFC3339)},
+				},
+			}})
+		default:
+			t.Errorf("Unexpected Forgejo request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer forgejoServer.Close()
+	forgejoURL = forgejoServer.URL
+
+	gitlabFails := true
+	gitlabServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gitlabFails {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v4/projects":
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": 7, "path": "notes", "visibility": "private", "default_branch": "main", "namespace": map[string]string{"full_path": "bnema"}},
+				{"id": 8, "path": "old-notes", "visibility": "private", "default_branch": "main", "archived": true, "namespace": map[string]string{"full_path": "bnema"}},
+			})
+		case "/api/v4/projects/7/repository/commits":
+			if r.URL.Query().Get("author") != "bnema" {
+				t.Errorf("Expected commits of the configured author, got %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode([]map[string]string{
+				{"id": "g1", "message": "docs: notes", "authored_date": "2024-08-02T12:00:00Z", "web_url": "https://gitlab.example/bnema/notes/-/commit/g1"},
+			})
+		default:
+			t.Errorf("Unexpected GitLab request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer gitlabServer.Close()
+
+	originalProviders, originalRules := commitProviders, repoRules
+	defer func() { commitProviders, repoRules = originalProviders, originalRules }()
+	// Rules apply to the repositories of author searches as well
+	repoRules, _ = NewRepoRules(config.RepoRules{ExcludeArchived: true})
+	err := InitCommitProviders(&config.Config{ForgeAccounts: []config.ForgeAccount{
+		{Forge: ForgeForgejo, URL: forgejoServer.URL, Token: "secret"},
+		{Forge: ForgeGitLab, URL: gitlabServer.URL, Token: "secret", Author: "bnema"},
+	}})
+	if err != nil {
+		t.Fatalf("InitCommitProviders returned an error: %v", err)
+	}
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	err = SyncCommitProviders(context.Background(), cache.Merge)
+	if err == nil || !strings.Contains(err.Error(), "gitlab:") {
+		t.Fatalf("Expected the GitLab account to fail, got %v", err)
+	}
+	if commit := cache.commits["shared"]; commit.Forge != ForgeForgejo || commit.RepoName != "gordon" {
+		t.Errorf("Expected the Forgejo commit to be cached despite the GitLab failure, got %+v", commit)
+	}
+	if _, ok := cache.commits["o1"]; !ok {
+		t.Error("Expected the commits of the other repositories to be cached despite the broken one")
+	}
+
+	gitlabFails = false
+	oldListed = false
+	if err := SyncCommitProviders(context.Background(), cache.Merge); err != nil {
+		t.Fatalf("SyncCommitProviders returned an error: %v", err)
+	}
+	if commit := cache.commits["g1"]; commit.Forge != ForgeGitLab || !commit.IsPrivate {
+		t.Errorf("Expected the GitLab commit to be tagged with its forge, got %+v", commit)
+	}
+	if _, ok := cache.commits["o1"]; ok {
+		t.Error("Expected the commits of a repository no longer listed to be removed")
+	}
+	if len(cache.commits) != 2 {
+		t.Errorf("Expected 2 cached commits, got %d", len(cache.commits))
+	}
+
+	// The first sync reads every commit, the next ones only the recent ones
+	if len(forgejoSince) != 2 || forgejoSince[0] != "" || forgejoSince[1] == "" {
+		t.Errorf("Expected an incremental second sync, got since %q", forgejoSince)
+	}
+	// A failed repository is fetched again in full
+	if len(brokenSince) != 2 || brokenSince[1] != "" {
+		t.Errorf("Expected the broken repository to be retried from scratch, got since %q", brokenSince)
+	}
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// GitHub rate limit resources, each one has its own budget
+const (
+	RateResourceCore       = "core"
+	RateResourceSearch     = "search"
+	RateResourceCodeSearch = "code_search"
+	RateResourceGraphQL    = "graphql"
+)
+
+const (
+	// rateLimitMaxWait is the longest a request waits for its budget, longer waits fail
+	// right away so API handlers are not held for an hour
+	rateLimitMaxWait = 2 * time.Minute
+	// secondaryLimitBackoff is the wait GitHub asks for when a secondary limit has no Retry-After
+	secondaryLimitBackoff = time.Minute
+	rateLimitMaxRetries   = 3
+)
+
+type rateBudget struct {
+	limit        int
+	remaining    int
+	used         int
+	reset        time.Time
+	known        bool
+	blockedUntil time.Time
+	waits        int
+	retries      int
+}
+
+// RateLimiter tracks the GitHub rate limit budget of every resource from response headers
+// and holds requests back until their budget is available again
+type RateLimiter struct {
+	mutex   sync.Mutex
+	budgets map[string]*rateBudget
+	maxWait time.Duration
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+var githubRateLimiter = NewRateLimiter()
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		budgets: make(map[string]*rateBudget),
+		maxWait: rateLimitMaxWait,
+		now:     time.Now,
+		sleep:   sleepContext,
+	}
+}
+
+// Transport wraps an HTTP transport so its requests go through the rate limiter
+func (l *RateLimiter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &rateLimitTransport{base: base, limiter: l}
+}
+
+// Remaining returns the requests left for a resource, known is false until GitHub reported it
+func (l *RateLimiter) Remaining(resource string) (remaining int, known bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	budget, ok := l.budgets[resource]
+	if !ok || !budget.known {
+		return 0, false
+	}
+	if !budget.reset.IsZero() && l.now().After(budget.reset) {
+		// The window was reset since the last response
+		return budget.limit, true
+	}
+	return budget.remaining, true
+}
+
+// Budgets returns the last known budget of every resource
+func (l *RateLimiter) Budgets() []models.RateLimitBudget {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	budgets := make([]models.RateLimitBudget, 0, len(l.budgets))
+	for resource, budget := range l.budgets {
+		status := models.RateLimitBudget{
+			Resource:  resource,
+			Limit:     budget.limit,
+			Remaining: budget.remaining,
+			Used:      budget.used,
+			Waits:     budget.waits,
+			Retries:   budget.retries,
+		}
+		if !budget.reset.IsZero() {
+			status.Reset = budget.reset.UTC().Format(time.RFC3339)
+		}
+		if budget.blockedUntil.After(l.now()) {
+			status.BlockedUntil = budget.blockedUntil.UTC().Format(time.RFC3339)
+		}
+		budgets = append(budgets, status)
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].Resource < budgets[j].Resource
+	})
+	return budgets
+}
+
+func (l *RateLimiter) budget(resource string) *rateBudget {
+	budget, ok := l.budgets[resource]
+	if !ok {
+		budget = &rateBudget{}
+		l.budgets[resource] = budget
+	}
+	return budget
+}
+
+// delay returns how long a request to the resource has to wait for its budget
+func (l *RateLimiter) delay(resource string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	budget, ok := l.budgets[resource]
+	if !ok {
+		return 0
+	}
+	now := l.now()
+	var until time.Time
+	if budget.blockedUntil.After(now) {
+		until = budget.blockedUntil
+	}
+	// GitHub resets the budget on the second, leave it a moment
+	if reset := budget.reset.Add(time.Second); budget.known && budget.remaining == 0 && reset.After(until) {
+		until = reset
+	}
+	if until.IsZero() || !until.After(now) {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+// observe records the rate limit headers of a response
+func (l *RateLimiter) observe(resource string, header http.Header) {
+	if name := header.Get("X-RateLimit-Resource"); name != "" {
+		resource = name
+	}
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	budget := l.budget(resource)
+	budget.known = true
+	budget.limit = limit
+	budget.remaining, _ = strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	budget.used, _ = strconv.Atoi(header.Get("X-RateLimit-Used"))
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		budget.reset = time.Unix(reset, 0)
+	}
+}
+
+// block holds back requests to the resource for the given duration
+func (l *RateLimiter) block(resource string, d time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	budget := l.budget(resource)
+	if until := l.now().Add(d); until.After(budget.blockedUntil) {
+		budget.blockedUntil = until
+	}
+}
+
+func (l *RateLimiter) count(resource string, waited, retried bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	budget := l.budget(resource)
+	if waited {
+		budget.waits++
+	}
+	if retried {
+		budget.retries++
+	}
+}
+
+// wait sleeps until the resource has budget again, unless that takes longer than the
+// maximum wait or the request deadline
+func (l *RateLimiter) wait(ctx context.Context, resource string) error {
+	d := l.delay(resource)
+	if d <= 0 || d > l.maxWait {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && l.now().Add(d).After(deadline) {
+		return nil
+	}
+	l.count(resource, true, false)
+	log.Debug("Waiting for GitHub rate limit", "resource", resource, "wait", d)
+	return l.sleep(ctx, d)
+}
+
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *RateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := rateResource(req.URL.Path)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.wait(req.Context(), resource); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 {
+			var err error
+			if attemptReq, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+		t.limiter.observe(resource, resp.Header)
+
+		retryAfter, limited := rateLimitedFor(resp)
+		if !limited {
+			return resp, nil
+		}
+		if retryAfter > 0 {
+			t.limiter.block(resource, retryAfter)
+		}
+		if attempt+1 >= rateLimitMaxRetries || !replayable(req) || t.limiter.delay(resource) > t.limiter.maxWait {
+			// go-github turns the response into a rate limit error
+			return resp, nil
+		}
+		t.limiter.count(resource, false, true)
+		resp.Body.Close()
+	}
+}
+
+// rateLimitedFor reports whether a response hit a primary or secondary rate limit, and the
+// wait GitHub asked for. A primary limit wait comes from the reset time of the budget instead.
+func rateLimitedFor(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return 0, true
+	}
+
+	// Secondary limits without Retry-After are only told apart by their message
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit") {
+		return secondaryLimitBackoff, true
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return secondaryLimitBackoff, true
+	}
+	return 0, false
+}
+
+// rateResource returns the rate limit resource a request counts against
+func rateResource(path string) string {
+	switch {
+	case strings.Contains(path, "/search/code"):
+		return RateResourceCodeSearch
+	case strings.Contains(path, "/search/"):
+		return RateResourceSearch
+	case strings.HasSuffix(path, "/graphql"):
+		return RateResourceGraphQL
+	default:
+		return RateResourceCore
+	}
+}
+
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindRequest clones a request with a fresh body so it can be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v63/github"
+)
+
+// newRateLimitedClient returns a GitHub client going through a rate limiter with a fake
+// clock, so waits are recorded instead of slept
+func newRateLimitedClient(t *testing.T, handler http.HandlerFunc) (*github.Client, *RateLimiter, *[]time.Duration) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+		return nil
+	}
+
+	client := github.NewClient(&http.Client{Transport: limiter.Transport(nil)})
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return client, limiter, &sleeps
+}
+
+func setRateHeaders(w http.ResponseWriter, resource string, remaining int, reset time.Time) {
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Used", strconv.Itoa(5000-remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	w.Header().Set("X-RateLimit-Resource", resource)
+}
+
+func TestRateLimiterWaitsForPrimaryReset(t *testing.T) {
+	reset := time.Date(2024, 8, 1, 12, 0, 30, 0, time.UTC)
+	calls := 0
+	client, limiter, sleeps := newRateLimitedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			setRateHeaders(w, "core", 0, reset)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+			return
+		}
+		setRateHeaders(w, "core", 4999, reset.Add(time.Hour))
+		w.Write([]byte(`{"login": "testuser"}`))
+	})
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Expected the request to succeed after the reset, got %v", err)
+	}
+	if user.GetLogin() != "testuser" || calls != 2 {
+		t.Errorf("Expected one retry, got %d calls", calls)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 31*time.Second {
+		t.Errorf("Expected to wait until the reset, got %v", *sleeps)
+	}
+
+	if remaining, known := limiter.Remaining(RateResourceCore); !known || remaining != 4999 {
+		t.Errorf("Expected 4999 core requests left, got %d (known: %v)", remaining, known)
+	}
+	budgets := limiter.Budgets()
+	if len(budgets) != 1 || budgets[0].Waits != 1 || budgets[0].Retries != 1 || budgets[0].Used != 1 {
+		t.Errorf("Unexpected budgets %+v", budgets)
+	}
+}
+
+func TestRateLimiterHonorsRetryAfter(t *testing.T) {
+	calls := 0
+	client, limiter, sleeps := newRateLimitedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		setRateHeaders(w, "search", 25, time.Date(2024, 8, 1, 12, 1, 0, 0, time.UTC))
+		if calls == 1 {
+			w.Header().Set("Retry-After", "10")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "You have exceeded a secondary rate limit"}`))
+			return
+		}
+		w.Write([]byte(`{"total_count": 0, "items": []}`))
+	})
+
+	if _, _, err := client.Search.Commits(context.Background(), "author:testuser", nil); err != nil {
+		t.Fatalf("Expected the search to succeed after the secondary limit, got %v", err)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 10*time.Second {
+		t.Errorf("Expected to wait for Retry-After, got %v", *sleeps)
+	}
+	if _, known := limiter.Remaining(RateResourceCore); known {
+		t.Error("Expected search requests to leave the core budget alone")
+	}
+	if remaining, _ := limiter.Remaining(RateResourceSearch); remaining != 25 {
+		t.Errorf("Expected 25 search requests left, got %d", remaining)
+	}
+}
+
+func TestRateLimiterGivesUpOnLongWaits(t *testing.T) {
+	calls := 0
+	client, _, sleeps := newRateLimitedClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		setRateHeaders(w, "core", 0, time.Date(2024, 8, 1, 13, 0, 0, 0, time.UTC))
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+	})
+
+	_, _, err := client.Users.Get(context.Background(), "")
+	var rateLimitErr *github.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+	if calls != 1 || len(*sleeps) != 0 {
+		t.Errorf("Expected no wait for an hour long reset, got %d calls and waits %v", calls, *sleeps)
+	}
+}
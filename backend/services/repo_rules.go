@@ -0,0 +1,159 @@
+package services
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// RepoRules selects the repositories commits are synced from and how their commits are
+// shown. The zero value allows every repository and keeps the forge visibility.
+type RepoRules struct {
+	include         []repoPattern
+	exclude         []repoPattern
+	excludeArchived bool
+	excludeForks    bool
+	overrides       []repoOverride
+}
+
+// repoPattern matches repositories by owner/name, see config.RepoRules
+type repoPattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+type repoOverride struct {
+	pattern  repoPattern
+	private  *bool
+	showName bool
+}
+
+var repoRules = &RepoRules{}
+
+// InitRepoRules applies the configured repository rules
+func InitRepoRules(cfg *config.Config) error {
+	rules, err := NewRepoRules(cfg.RepoRules)
+	if err != nil {
+		return err
+	}
+	repoRules = rules
+	if len(rules.include)+len(rules.exclude)+len(rules.overrides) > 0 || rules.excludeArchived || rules.excludeForks {
+		log.Info("Repository rules enabled", "include", len(rules.include), "exclude", len(rules.exclude), "overrides", len(rules.overrides))
+	}
+	return nil
+}
+
+func NewRepoRules(cfg config.RepoRules) (*RepoRules, error) {
+	rules := &RepoRules{excludeArchived: cfg.ExcludeArchived, excludeForks: cfg.ExcludeForks}
+	var err error
+	if rules.include, err = newRepoPatterns(cfg.Include); err != nil {
+		return nil, err
+	}
+	if rules.exclude, err = newRepoPatterns(cfg.Exclude); err != nil {
+		return nil, err
+	}
+	for _, override := range cfg.Overrides {
+		pattern, err := newRepoPattern(override.Repo)
+		if err != nil {
+			return nil, err
+		}
+		rules.overrides = append(rules.overrides, repoOverride{pattern: pattern, private: override.Private, showName: override.ShowName})
+	}
+	return rules, nil
+}
+
+func newRepoPatterns(patterns []string) ([]repoPattern, error) {
+	compiled := make([]repoPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := newRepoPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+func newRepoPattern(pattern string) (repoPattern, error) {
+	if err := config.ValidateRepoPattern(pattern); err != nil {
+		return repoPattern{}, err
+	}
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		return repoPattern{re: regexp.MustCompile("(?i)" + expr)}, nil
+	}
+	return repoPattern{glob: strings.ToLower(pattern)}, nil
+}
+
+// match reports whether the pattern matches a repository, ignoring case
+func (p repoPattern) match(fullName string) bool {
+	if p.re != nil {
+		return p.re.MatchString(fullName)
+	}
+	name := strings.ToLower(fullName)
+	if !strings.Contains(p.glob, "/") {
+		name = name[strings.LastIndex(name, "/")+1:]
+	}
+	matched, _ := path.Match(p.glob, name)
+	return matched
+}
+
+func matchAny(patterns []repoPattern, fullName string) bool {
+	for _, pattern := range patterns {
+		if pattern.match(fullName) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the commits of a repository are synced
+func (r *RepoRules) Allows(fullName string, archived, fork bool) bool {
+	if (archived && r.excludeArchived) || (fork && r.excludeForks) {
+		return false
+	}
+	if len(r.include) > 0 && !matchAny(r.include, fullName) {
+		return false
+	}
+	return !matchAny(r.exclude, fullName)
+}
+
+// visibility returns whether a commit is shown as private and, if so, whether its
+// repository name stays readable
+func (r *RepoRules) visibility(commit models.Commit) (private, showName bool) {
+	private = commit.IsPrivate
+	fullName := commitRepoFullName(commit)
+	for _, override := range r.overrides {
+		if !override.pattern.match(fullName) {
+			continue
+		}
+		if override.private != nil {
+			private = *override.private
+		}
+		return private, override.showName
+	}
+	return private, false
+}
+
+// commitRepoFullName returns the owner/name of the repository of a commit from its URL,
+// such as https://github.com/owner/repo/commit/sha or https://gitlab.com/group/repo/-/commit/sha.
+// Commits without URL, such as those of local repositories, only have their name.
+func commitRepoFullName(commit models.Commit) string {
+	u, err := url.Parse(commit.URL)
+	if err != nil || u.Host == "" {
+		return commit.RepoName
+	}
+	repoPath := strings.Trim(u.Path, "/")
+	if i := strings.Index(repoPath, "/-/commit/"); i >= 0 {
+		return repoPath[:i]
+	}
+	if i := strings.LastIndex(repoPath, "/commit/"); i >= 0 {
+		return repoPath[:i]
+	}
+	return commit.RepoName
+}
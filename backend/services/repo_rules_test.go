@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+)
+
+func TestRepoRulesAllows(t *testing.T) {
+	rules, err := NewRepoRules(config.RepoRules{
+		Include:         []string{"bnema/*", "re:^acme/(api|web)$"},
+		Exclude:         []string{"*-sandbox", "bnema/dotfiles"},
+		ExcludeArchived: true,
+		ExcludeForks:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewRepoRules returned an error: %v", err)
+	}
+
+	tests := []struct {
+		fullName       string
+		archived, fork bool
+		allowed        bool
+	}{
+		{fullName: "bnema/gordon", allowed: true},
+		{fullName: "BNEMA/Gordon", allowed: true},
+		{fullName: "acme/api", allowed: true},
+		{fullName: "acme/api-legacy"},
+		{fullName: "other/gordon"},
+		{fullName: "bnema/go-sandbox"},
+		{fullName: "bnema/dotfiles"},
+		{fullName: "bnema/gart", archived: true},
+		{fullName: "bnema/gart", fork: true},
+	}
+	for _, tt := range tests {
+		if got := rules.Allows(tt.fullName, tt.archived, tt.fork); got != tt.allowed {
+			t.Errorf("Allows(%q, archived=%t, fork=%t) = %t, expected %t", tt.fullName, tt.archived, tt.fork, got, tt.allowed)
+		}
+	}
+
+	if !(&RepoRules{}).Allows("anyone/anything", true, true) {
+		t.Error("Expected empty rules to allow every repository")
+	}
+	if _, err := NewRepoRules(config.RepoRules{Exclude: []string{"re:("}}); err == nil {
+		t.Error("Expected an invalid regexp to be rejected")
+	}
+}
+
+func TestObfuscatePrivateCommitsAppliesOverrides(t *testing.T) {
+	private := true
+	rules, err := NewRepoRules(config.RepoRules{Overrides: []config.RepoOverride{
+		{Repo: "bnema/client-*", Private: &private},
+		{Repo: "group/sub/internal-tool", ShowName: true},
+	}})
+	if err != nil {
+		t.Fatalf("NewRepoRules returned an error: %v", err)
+	}
+
+	originalRules, originalSettings := repoRules, obfuscation
+	defer func() { repoRules, obfuscation = originalRules, originalSettings }()
+	repoRules = rules
+	obfuscation = obfuscationSettings{key: []byte("server-secret"), mode: ObfuscationLength}
+
+	commits := ObfuscatePrivateCommits([]models.Commit{
+		{ID: "a", RepoName: "client-site", Message: "feat: a", URL: "https://github.com/bnema/client-site/commit/a"},
+		{ID: "b", RepoName: "internal-tool", Message: "feat: b", URL: "https://gitlab.com/group/sub/internal-tool/-/commit/b", IsPrivate: true},
+		{ID: "c", RepoName: "gordon", Message: "feat: c", URL: "https://github.com/bnema/gordon/commit/c"},
+	})
+
+	if forced := commits[0]; !forced.IsPrivate || forced.URL != "#" || strings.Contains(forced.RepoName, "client") {
+		t.Errorf("Expected the public repository to be treated as private, got %+v", forced)
+	}
+	if named := commits[1]; !named.IsPrivate || named.RepoName != "internal-tool" || named.URL != "#" || named.Message == "feat: b" {
+		t.Errorf("Expected only the repository name to stay readable, got %+v", named)
+	}
+	if untouched := commits[2]; untouched.IsPrivate || untouched.ID != "c" {
+		t.Errorf("Expected commits without override to be untouched, got %+v", untouched)
+	}
+}
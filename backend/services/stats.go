@@ -0,0 +1,196 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+const (
+	heatmapDays         = 365
+	mostActiveRepoCount = 5
+	// privateRepoBucket groups every private repository so names never leak through stats
+	privateRepoBucket = "private"
+	// unknownLanguage groups enriched commits of repositories without a detected language
+	unknownLanguage = "unknown"
+	dayLayout       = "2006-01-02"
+)
+
+type statsSnapshot struct {
+	stats    models.CommitStats
+	source   *CommitCache
+	revision uint64
+	day      string
+}
+
+var (
+	statsCache      statsSnapshot
+	statsCacheMutex sync.Mutex
+)
+
+// GetCommitStats returns statistics over the cached commits. They are recomputed only when
+// the cached commits changed or a new day started.
+func GetCommitStats() models.CommitStats {
+	now := time.Now().UTC()
+	source := cache
+	revision := source.GetRevision()
+
+	statsCacheMutex.Lock()
+	defer statsCacheMutex.Unlock()
+
+	if statsCache.source == source && statsCache.revision == revision && statsCache.day == now.Format(dayLayout) {
+		return statsCache.stats
+	}
+
+	stats := computeCommitStats(now)
+	statsCache = statsSnapshot{stats: stats, source: source, revision: revision, day: now.Format(dayLayout)}
+	return stats
+}
+
+// computeCommitStats aggregates the cached commits as seen at the given time
+func computeCommitStats(now time.Time) models.CommitStats {
+	cache.mutex.RLock()
+	entries := cache.readIndex().ordered
+	perDay := make(map[string]int)
+	perRepo := make(map[string]*models.RepoStats)
+	perLanguage := make(map[string]*models.LanguageStats)
+	enriched := 0
+	var distribution models.Distribution
+	for _, entry := range entries {
+		t := entry.time.UTC()
+		perDay[t.Format(dayLayout)]++
+		distribution.Weekdays[t.Weekday()]++
+		distribution.Hours[t.Hour()]++
+
+		// Private repos are counted together, unless their name is shown anyway
+		name := entry.public.RepoName
+		if entry.public.IsPrivate && name != entry.commit.RepoName {
+			name = privateRepoBucket
+		}
+		repo, ok := perRepo[name]
+		if !ok {
+			// Entries are newest first, so the first commit seen is the latest one
+			repo = &models.RepoStats{RepoName: name, IsPrivate: entry.public.IsPrivate, LastCommit: entry.public.Timestamp}
+			perRepo[name] = repo
+		}
+		repo.Commits++
+
+		if changes := entry.commit.Changes; changes != nil {
+			enriched++
+			language := entry.commit.Language
+			if language == "" {
+				language = unknownLanguage
+			}
+			stats, ok := perLanguage[language]
+			if !ok {
+				stats = &models.LanguageStats{Language: language}
+				perLanguage[language] = stats
+			}
+			stats.Commits++
+			stats.Additions += changes.Additions
+			stats.Deletions += changes.Deletions
+			stats.FilesChanged += changes.FilesChanged
+		}
+	}
+	total := len(entries)
+	cache.mutex.RUnlock()
+
+	repos := make([]models.RepoStats, 0, len(perRepo))
+	for _, repo := range perRepo {
+		repos = append(repos, *repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].Commits != repos[j].Commits {
+			return repos[i].Commits > repos[j].Commits
+		}
+		return repos[i].RepoName < repos[j].RepoName
+	})
+
+	// The anonymous bucket is not a repository the visitor can look at
+	var mostActive []models.RepoStats
+	for _, repo := range repos {
+		if len(mostActive) == mostActiveRepoCount {
+			break
+		}
+		if !repo.IsPrivate {
+			mostActive = append(mostActive, repo)
+		}
+	}
+
+	languages := make([]models.LanguageStats, 0, len(perLanguage))
+	for _, language := range perLanguage {
+		languages = append(languages, *language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Commits != languages[j].Commits {
+			return languages[i].Commits > languages[j].Commits
+		}
+		return languages[i].Language < languages[j].Language
+	})
+
+	return models.CommitStats{
+		TotalCommits:    total,
+		EnrichedCommits: enriched,
+		Heatmap:         buildHeatmap(perDay, now),
+		Repos:           repos,
+		MostActiveRepos: mostActive,
+		Distribution:    distribution,
+		Streaks:         computeStreaks(perDay, now),
+		Languages:       languages,
+		GeneratedAt:     now.Format(time.RFC3339),
+	}
+}
+
+// buildHeatmap returns one entry per day of the last year, oldest first, including empty days
+func buildHeatmap(perDay map[string]int, now time.Time) []models.HeatmapDay {
+	today := now.Truncate(24 * time.Hour)
+	heatmap := make([]models.HeatmapDay, 0, heatmapDays)
+	for i := heatmapDays - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format(dayLayout)
+		heatmap = append(heatmap, models.HeatmapDay{Date: day, Count: perDay[day]})
+	}
+	return heatmap
+}
+
+// computeStreaks finds runs of consecutive days with commits. Like on GitHub, the current
+// streak is still running when the last commit was made yesterday.
+func computeStreaks(perDay map[string]int, now time.Time) models.Streaks {
+	days := make([]time.Time, 0, len(perDay))
+	for day := range perDay {
+		t, err := time.Parse(dayLayout, day)
+		if err == nil {
+			days = append(days, t)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	var streaks models.Streaks
+	runLength := 0
+	var runStart time.Time
+	for i, day := range days {
+		if i > 0 && day.Sub(days[i-1]) == 24*time.Hour {
+			runLength++
+		} else {
+			runLength = 1
+			runStart = day
+		}
+		if runLength > streaks.Longest {
+			streaks.Longest = runLength
+			streaks.LongestStart = runStart.Format(dayLayout)
+			streaks.LongestEnd = day.Format(dayLayout)
+		}
+	}
+
+	if len(days) > 0 {
+		today := now.Truncate(24 * time.Hour)
+		last := days[len(days)-1]
+		if last.Equal(today) || last.Equal(today.AddDate(0, 0, -1)) {
+			streaks.Current = runLength
+		}
+	}
+	return streaks
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+)
+
+func TestComputeCommitStats(t *testing.T) {
+	now := time.Date(2024, 8, 10, 18, 0, 0, 0, time.UTC)
+	at := func(days, hour int) string {
+		return time.Date(2024, 8, 10+days, hour, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	}
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+
+	cache.Update([]models.Commit{
+		// A three day streak ending yesterday
+		{ID: "a", RepoName: "gordon", Message: "feat: a", Timestamp: at(-1, 9)},
+		{ID: "b", RepoName: "gordon", Message: "feat: b", Timestamp: at(-2, 9)},
+		{ID: "c", RepoName: "gart", Message: "fix: c", Timestamp: at(-3, 14)},
+		// A longer streak earlier on
+		{ID: "d", RepoName: "gart", Message: "fix: d", Timestamp: at(-10, 14)},
+		{ID: "e", RepoName: "secret", Message: "wip", Timestamp: at(-11, 22), IsPrivate: true},
+		{ID: "f", RepoName: "other-secret", Message: "wip", Timestamp: at(-12, 22), IsPrivate: true},
+		{ID: "g", RepoName: "gordon", Message: "feat: g", Timestamp: at(-13, 9)},
+		// Out of the heatmap range
+		{ID: "h", RepoName: "gordon", Message: "feat: h", Timestamp: at(-400, 9)},
+	})
+
+	stats := computeCommitStats(now)
+
+	if stats.TotalCommits != 8 {
+		t.Errorf("Expected 8 commits, got %d", stats.TotalCommits)
+	}
+
+	if len(stats.Heatmap) != heatmapDays {
+		t.Fatalf("Expected %d heatmap days, got %d", heatmapDays, len(stats.Heatmap))
+	}
+	last := stats.Heatmap[len(stats.Heatmap)-1]
+	if last.Date != "2024-08-10" || last.Count != 0 {
+		t.Errorf("Expected the heatmap to end today without commits, got %+v", last)
+	}
+	if day := stats.Heatmap[len(stats.Heatmap)-2]; day.Date != "2024-08-09" || day.Count != 1 {
+		t.Errorf("Expected one commit yesterday, got %+v", day)
+	}
+
+	expectedRepos := []models.RepoStats{
+		{RepoName: "gordon", Commits: 4, LastCommit: at(-1, 9)},
+		{RepoName: "gart", Commits: 2, LastCommit: at(-3, 14)},
+		{RepoName: privateRepoBucket, Commits: 2, IsPrivate: true, LastCommit: at(-11, 22)},
+	}
+	if len(stats.Repos) != len(expectedRepos) {
+		t.Fatalf("Expected %d repos, got %+v", len(expectedRepos), stats.Repos)
+	}
+	for i, repo := range expectedRepos {
+		if stats.Repos[i] != repo {
+			t.Errorf("Expected repo %d to be %+v, got %+v", i, repo, stats.Repos[i])
+		}
+	}
+	if len(stats.MostActiveRepos) != 2 {
+		t.Errorf("Expected the private bucket to be left out of the most active repos, got %+v", stats.MostActiveRepos)
+	}
+
+	if stats.Distribution.Hours[9] != 4 || stats.Distribution.Hours[14] != 2 || stats.Distribution.Hours[22] != 2 {
+		t.Errorf("Unexpected hour distribution: %v", stats.Distribution.Hours)
+	}
+	// 2024-08-09 and 2023-07-07 are Fridays
+	if stats.Distribution.Weekdays[time.Friday] != 2 || stats.Distribution.Weekdays[time.Saturday] != 0 {
+		t.Errorf("Unexpected weekday distribution: %v", stats.Distribution.Weekdays)
+	}
+
+	expectedStreaks := models.Streaks{Current: 3, Longest: 4, LongestStart: "2024-07-28", LongestEnd: "2024-07-31"}
+	if stats.Streaks != expectedStreaks {
+		t.Errorf("Expected streaks %+v, got %+v", expectedStreaks, stats.Streaks)
+	}
+
+	// Two days without commits break the current streak
+	if streaks := computeCommitStats(now.AddDate(0, 0, 2)).Streaks; streaks.Current != 0 {
+		t.Errorf("Expected the current streak to be broken, got %d", streaks.Current)
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"portfolio-backend/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// CommitStore is the storage backend behind the commit cache
+type CommitStore interface {
+	// Load returns every stored commit and the time of the last successful update
+	Load() ([]models.Commit, time.Time, error)
+	// Save persists the given commits along with the last update time
+	Save(commits []models.Commit, lastUpdated time.Time) error
+	// Delete removes the commits with the given IDs
+	Delete(ids []string) error
+	// LoadRepos returns the stored sync state of every repository
+	LoadRepos() ([]models.RepoSyncState, error)
+	// SaveRepos replaces the stored repository sync states
+	SaveRepos(repos []models.RepoSyncState) error
+	// LoadProviders returns the stored sync state of every commit provider account
+	LoadProviders() ([]models.ProviderSyncState, error)
+	// SaveProvider persists the sync state of a commit provider account
+	SaveProvider(provider models.ProviderSyncState) error
+	Close() error
+}
+
+// NewCommitStore creates the store matching the configured backend
+func NewCommitStore(backend, path string) (CommitStore, error) {
+	switch backend {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "bolt":
+		return NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", backend)
+	}
+}
+
+// MemoryStore keeps commits in a map, nothing survives a restart
+type MemoryStore struct {
+	commits     map[string]models.Commit
+	repos       []models.RepoSyncState
+	providers   map[string]models.ProviderSyncState
+	lastUpdated time.Time
+	mutex       sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		commits:   make(map[string]models.Commit),
+		providers: make(map[string]models.ProviderSyncState),
+	}
+}
+
+func (s *MemoryStore) Load() ([]models.Commit, time.Time, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	commits := make([]models.Commit, 0, len(s.commits))
+	for _, commit := range s.commits {
+		commits = append(commits, commit)
+	}
+	return commits, s.lastUpdated, nil
+}
+
+func (s *MemoryStore) Save(commits []models.Commit, lastUpdated time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, commit := range commits {
+		s.commits[commit.ID] = commit
+	}
+	s.lastUpdated = lastUpdated
+	return nil
+}
+
+func (s *MemoryStore) Delete(ids []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, id := range ids {
+		delete(s.commits, id)
+	}
+	return nil
+}
+
+func (s *MemoryStore) LoadRepos() ([]models.RepoSyncState, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]models.RepoSyncState{}, s.repos...), nil
+}
+
+func (s *MemoryStore) SaveRepos(repos []models.RepoSyncState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.repos = append([]models.RepoSyncState{}, repos...)
+	return nil
+}
+
+func (s *MemoryStore) LoadProviders() ([]models.ProviderSyncState, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	providers := make([]models.ProviderSyncState, 0, len(s.providers))
+	for _, provider := range s.providers {
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+func (s *MemoryStore) SaveProvider(provider models.ProviderSyncState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.providers[provider.Key] = provider
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+var (
+	commitsBucket   = []byte("commits")
+	reposBucket     = []byte("repos")
+	providersBucket = []byte("providers")
+	metaBucket      = []byte("meta")
+	lastUpdateKey   = []byte("last_updated")
+)
+
+// BoltStore persists commits in a bbolt database file
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{commitsBucket, reposBucket, providersBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize cache database: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Load() ([]models.Commit, time.Time, error) {
+	var commits []models.Commit
+	var lastUpdated time.Time
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(commitsBucket).ForEach(func(_, v []byte) error {
+			var commit models.Commit
+			if err := json.Unmarshal(v, &commit); err != nil {
+				return err
+			}
+			commits = append(commits, commit)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if raw := tx.Bucket(metaBucket).Get(lastUpdateKey); raw != nil {
+			return lastUpdated.UnmarshalText(raw)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to load commits from cache database: %w", err)
+	}
+
+	return commits, lastUpdated, nil
+}
+
+func (s *BoltStore) Save(commits []models.Commit, lastUpdated time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(commitsBucket)
+		for _, commit := range commits {
+			data, err := json.Marshal(commit)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(commit.ID), data); err != nil {
+				return err
+			}
+		}
+
+		raw, err := lastUpdated.MarshalText()
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(metaBucket).Put(lastUpdateKey, raw)
+	})
+}
+
+func (s *BoltStore) Delete(ids []string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(commitsBucket)
+		for _, id := range ids {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) LoadRepos() ([]models.RepoSyncState, error) {
+	var repos []models.RepoSyncState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(reposBucket).ForEach(func(_, v []byte) error {
+			var repo models.RepoSyncState
+			if err := json.Unmarshal(v, &repo); err != nil {
+				return err
+			}
+			repos = append(repos, repo)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load repositories from cache database: %w", err)
+	}
+	return repos, nil
+}
+
+func (s *BoltStore) SaveRepos(repos []models.RepoSyncState) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(reposBucket); err != nil {
+			return err
+		}
+		bucket, err := tx.CreateBucket(reposBucket)
+		if err != nil {
+			return err
+		}
+		for _, repo := range repos {
+			data, err := json.Marshal(repo)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(strconv.FormatInt(repo.ID, 10)), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) LoadProviders() ([]models.ProviderSyncState, error) {
+	var providers []models.ProviderSyncState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(providersBucket).ForEach(func(_, v []byte) error {
+			var provider models.ProviderSyncState
+			if err := json.Unmarshal(v, &provider); err != nil {
+				return err
+			}
+			providers = append(providers, provider)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commit providers from cache database: %w", err)
+	}
+	return providers, nil
+}
+
+func (s *BoltStore) SaveProvider(provider models.ProviderSyncState) error {
+	data, err := json.Marshal(provider)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(providersBucket).Put([]byte(provider.Key), data)
+	})
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
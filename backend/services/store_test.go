@@ -0,0 +1,78 @@
+package services
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+)
+
+func TestBoltStorePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commits.db")
+	lastUpdated := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	commit := models.Commit{
+		ID:        "abc123",
+		RepoName:  "test-repo",
+		Message:   "Test commit",
+		Timestamp: lastUpdated.Add(-time.Hour).Format(time.RFC3339),
+		URL:       "https://github.com/bnema/test-repo/commit/abc123",
+	}
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore returned an error: %v", err)
+	}
+	if err := store.Save([]models.Commit{commit}, lastUpdated); err != nil {
+		t.Fatalf("Save returned an error: %v", err)
+	}
+	repo := models.RepoSyncState{ID: 42, Owner: "bnema", Name: "test-repo", DefaultBranch: "main", LastSeenSHA: "abc123"}
+	if err := store.SaveRepos([]models.RepoSyncState{repo}); err != nil {
+		t.Fatalf("SaveRepos returned an error: %v", err)
+	}
+	if err := store.SaveProvider(models.ProviderSyncState{Key: ForgeLocal, SyncedAt: lastUpdated.Format(time.RFC3339)}); err != nil {
+		t.Fatalf("SaveProvider returned an error: %v", err)
+	}
+	store.Close()
+
+	// Restoring the cache from the same file should skip the full crawl
+	originalCache := cache
+	defer func() { cache = originalCache }()
+
+	cfg := &config.Config{CacheBackend: "bolt", CachePath: path}
+	if err := InitCommitCache(cfg); err != nil {
+		t.Fatalf("InitCommitCache returned an error: %v", err)
+	}
+	defer CloseCommitCache()
+
+	if len(cache.commits) != 1 {
+		t.Fatalf("Expected 1 restored commit, got %d", len(cache.commits))
+	}
+	if !reflect.DeepEqual(cache.commits["abc123"], commit) {
+		t.Errorf("Expected commit %+v, got %+v", commit, cache.commits["abc123"])
+	}
+	if !cache.GetLastUpdated().Equal(lastUpdated) {
+		t.Errorf("Expected last update %v, got %v", lastUpdated, cache.GetLastUpdated())
+	}
+	if !reflect.DeepEqual(cache.repos[42], repo) {
+		t.Errorf("Expected repository state %+v, got %+v", repo, cache.repos[42])
+	}
+
+	// Commit providers resume from their last sync instead of fetching every commit again
+	originalProviders := commitProviders
+	defer func() { commitProviders = originalProviders }()
+	if err := InitCommitProviders(&config.Config{LocalRepoDirs: []string{t.TempDir()}}); err != nil {
+		t.Fatalf("InitCommitProviders returned an error: %v", err)
+	}
+	if len(commitProviders) != 1 || !commitProviders[0].since("gart").Equal(lastUpdated.Add(-syncOverlap)) {
+		t.Errorf("Expected the local provider to resume from %v, got %+v", lastUpdated, commitProviders)
+	}
+}
+
+func TestNewCommitStoreUnknownBackend(t *testing.T) {
+	if _, err := NewCommitStore("redis", ""); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"portfolio-backend/config"
+	"portfolio-backend/models"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v63/github"
+)
+
+var (
+	// ErrWebhookDisabled is returned when no webhook secret is configured
+	ErrWebhookDisabled = errors.New("GitHub webhooks are not enabled")
+	// ErrInvalidSignature is returned when the payload signature does not match the secret
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrInvalidPayload is returned when a verified payload cannot be parsed
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+)
+
+type webhookSettings struct {
+	secret       []byte
+	contentOwner string
+	contentRepo  string
+	contentPath  string
+}
+
+var webhook webhookSettings
+
+var (
+	authenticatedLogin      string
+	authenticatedLoginMutex sync.Mutex
+)
+
+// InitGitHubWebhook configures the secret used to verify GitHub webhook deliveries
+func InitGitHubWebhook(cfg *config.Config) {
+	webhook = webhookSettings{
+		secret:       []byte(cfg.GitHubWebhookSecret),
+		contentOwner: cfg.ContentOwner,
+		contentRepo:  cfg.ContentRepo,
+		contentPath:  cfg.ContentPath,
+	}
+}
+
+// HandleGitHubWebhook verifies a webhook delivery and applies it to the caches.
+// It returns the event type of the delivery.
+func HandleGitHubWebhook(r *http.Request) (string, error) {
+	if len(webhook.secret) == 0 {
+		return "", ErrWebhookDisabled
+	}
+
+	payload, err := github.ValidatePayload(r, webhook.secret)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+	}
+
+	eventType := github.WebHookType(r)
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		return eventType, fmt.Errorf("%w: %s event: %s", ErrInvalidPayload, eventType, err)
+	}
+
+	switch event := event.(type) {
+	case *github.PushEvent:
+		return eventType, handlePushEvent(r.Context(), event)
+	default:
+		// Ping and other events need no processing
+		return eventType, nil
+	}
+}
+
+func handlePushEvent(ctx context.Context, event *github.PushEvent) error {
+	repo := event.GetRepo()
+
+	if repo.GetOwner().GetLogin() == webhook.contentOwner && repo.GetName() == webhook.contentRepo && touchesPath(event, webhook.contentPath) {
+		log.Info("Project content changed, refreshing project cache", "ref", event.GetRef())
+		go func() {
+			if err := UpdateProjectCache(); err != nil {
+				log.Error("Error refreshing project cache", "error", err)
+			}
+		}()
+	}
+
+	// Pushes are ingested from the same branches the crawler syncs
+	branch, isBranch := strings.CutPrefix(event.GetRef(), "refs/heads/")
+	if !isBranch || !crawler.syncsBranch(repo.GetDefaultBranch(), branch) {
+		return nil
+	}
+	if !repoRules.Allows(repo.GetOwner().GetLogin()+"/"+repo.GetName(), repo.GetArchived(), repo.GetFork()) {
+		return nil
+	}
+
+	login, err := getAuthenticatedLogin(ctx)
+	if err != nil {
+		return err
+	}
+
+	var commits []models.Commit
+	for _, commit := range event.Commits {
+		if !commit.GetDistinct() {
+			continue
+		}
+		account, ok := attributePushCommit(login, commit)
+		if !ok {
+			continue
+		}
+		commits = append(commits, models.Commit{
+			ID:        commit.GetID(),
+			RepoName:  repo.GetName(),
+			Message:   commit.GetMessage(),
+			Timestamp: commit.GetTimestamp().Format(time.RFC3339),
+			URL:       commit.GetURL(),
+			IsPrivate: repo.GetPrivate(),
+			Forge:     ForgeGitHub,
+			Account:   account,
+			Branches:  []string{branch},
+		})
+	}
+
+	if len(commits) == 0 {
+		return nil
+	}
+
+	// Other repositories may have been pushed to since the last sync, only the scheduled
+	// sync moves the last update time
+	cache.Merge(commits)
+	log.Info("Commits ingested from webhook", "repo", repo.GetFullName(), "new_commits", len(commits))
+	return nil
+}
+
+// touchesPath reports whether any commit of the push added, modified or removed a file under dir
+func touchesPath(event *github.PushEvent, dir string) bool {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for _, commit := range event.Commits {
+		for _, files := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, file := range files {
+				if strings.HasPrefix(file, prefix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// attributePushCommit returns the account a pushed commit belongs to, matched on the login
+// of the token owner or on the identities of the configured accounts
+func attributePushCommit(login string, commit *github.HeadCommit) (string, bool) {
+	author := commit.GetAuthor()
+	for i, account := range githubAccounts {
+		accountLogin := account.login
+		if i == 0 {
+			accountLogin = login
+		}
+		if (author.GetLogin() != "" && strings.EqualFold(author.GetLogin(), accountLogin)) || matchAuthor(author.GetEmail(), account.emails...) {
+			return account.name, true
+		}
+	}
+	return "", false
+}
+
+// getAuthenticatedLogin returns the login of the token owner, looked up once
+func getAuthenticatedLogin(ctx context.Context) (string, error) {
+	authenticatedLoginMutex.Lock()
+	defer authenticatedLoginMutex.Unlock()
+
+	if authenticatedLogin != "" {
+		return authenticatedLogin, nil
+	}
+
+	client := GetGitHubClient()
+	if client == nil {
+		return "", errors.New("GitHub client is not initialized")
+	}
+
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get authenticated user: %w", err)
+	}
+
+	authenticatedLogin = user.GetLogin()
+	return authenticatedLogin, nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-backend/models"
+
+	"github.com/google/go-github/v63/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+const pushPayload = `{
+	"ref": "refs/heads/main",
+	"repository": {
+		"name": "gordon",
+		"full_name": "testuser/gordon",
+		"private": false,
+		"default_branch": "main",
+		"owner": {"login": "testuser"}
+	},
+	"commits": [
+		{
+			"id": "abc123",
+			"distinct": true,
+			"message": "feat: add webhook support",
+			"timestamp": "2024-08-01T12:00:00Z",
+			"url": "https://github.com/testuser/gordon/commit/abc123",
+			"author": {"name": "Test", "email": "test@example.com", "username": "testuser"}
+		},
+		{
+			"id": "def456",
+			"distinct": true,
+			"message": "fix: someone else's work",
+			"timestamp": "2024-08-01T12:05:00Z",
+			"url": "https://github.com/testuser/gordon/commit/def456",
+			"author": {"name": "Other", "email": "other@example.com", "username": "other"}
+		}
+	]
+}`
+
+func newWebhookRequest(payload, secret string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/webhooks/github", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestHandleGitHubWebhookPush(t *testing.T) {
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetUser,
+			github.User{Login: github.String("testuser")},
+		),
+	)
+
+	originalClient := githubClient
+	githubClient = github.NewClient(mockedHTTPClient)
+	defer func() { githubClient = originalClient }()
+
+	originalCache := cache
+	cache = &CommitCache{commits: make(map[string]models.Commit)}
+	defer func() { cache = originalCache }()
+	lastUpdated := time.Date(2024, 8, 1, 11, 0, 0, 0, time.UTC)
+	cache.MarkUpdated(lastUpdated)
+
+	originalCrawler := crawler
+	crawler = NewCrawler(1, time.Minute)
+	crawler.branches = []string{"feat/*"}
+	defer func() { crawler = originalCrawler }()
+
+	originalWebhook := webhook
+	webhook = webhookSettings{secret: []byte("s3cret")}
+	defer func() { webhook = originalWebhook }()
+
+	authenticatedLogin = ""
+	defer func() { authenticatedLogin = "" }()
+
+	if _, err := HandleGitHubWebhook(newWebhookRequest(pushPayload, "wrong")); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected ErrInvalidSignature, got %v", err)
+	}
+	if len(cache.commits) != 0 {
+		t.Fatalf("Expected no commits from an unsigned delivery, got %d", len(cache.commits))
+	}
+
+	eventType, err := HandleGitHubWebhook(newWebhookRequest(pushPayload, "s3cret"))
+	if err != nil {
+		t.Fatalf("HandleGitHubWebhook returned an error: %v", err)
+	}
+	if eventType != "push" {
+		t.Errorf("Expected push event, got %s", eventType)
+	}
+
+	if len(cache.commits) != 1 {
+		t.Fatalf("Expected only the authenticated user's commit, got %d commits", len(cache.commits))
+	}
+	commit := cache.commits["abc123"]
+	if commit.RepoName != "gordon" || commit.Message != "feat: add webhook support" || commit.Timestamp != "2024-08-01T12:00:00Z" {
+		t.Errorf("Unexpected commit %+v", commit)
+	}
+	// Pushes elsewhere since the last sync are still to be fetched by the next one
+	if !cache.GetLastUpdated().Equal(lastUpdated) {
+		t.Errorf("Expected the last update time to be kept, got %s", cache.GetLastUpdated())
+	}
+
+	featurePush := strings.Replace(strings.Replace(pushPayload, "refs/heads/main", "refs/heads/feat/x", 1), "abc123", "fea789", -1)
+	if _, err := HandleGitHubWebhook(newWebhookRequest(featurePush, "s3cret")); err != nil {
+		t.Fatalf("HandleGitHubWebhook returned an error: %v", err)
+	}
+	if branches := cache.commits["fea789"].Branches; len(branches) != 1 || branches[0] != "feat/x" {
+		t.Errorf("Expected the push to a synced branch to be ingested, got %v", branches)
+	}
+
+	otherPush := strings.Replace(strings.Replace(pushPayload, "refs/heads/main", "refs/heads/docs", 1), "abc123", "d0c000", -1)
+	if _, err := HandleGitHubWebhook(newWebhookRequest(otherPush, "s3cret")); err != nil {
+		t.Fatalf("HandleGitHubWebhook returned an error: %v", err)
+	}
+	if _, ok := cache.commits["d0c000"]; ok {
+		t.Error("Expected pushes to branches that are not synced to be ignored")
+	}
+}